@@ -1,15 +1,20 @@
 package main
 
 import (
+	"cmp"
 	"context"
+	"flag"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/joho/godotenv"
 	"golang.org/x/term"
 
+	"github.com/CaptainFallaway/Discorder/internal/archive"
 	"github.com/CaptainFallaway/Discorder/internal/cli"
 	"github.com/CaptainFallaway/Discorder/internal/discord"
+	"github.com/CaptainFallaway/Discorder/internal/download"
 )
 
 func run(token, action string, args []string) error {
@@ -63,21 +68,452 @@ func run(token, action string, args []string) error {
 			return fmt.Errorf("channel ID is required to dump messages")
 		}
 		channelID := args[0]
-		messages, err := cli.GetAllMessages(dc, channelID)
+		flags, err := parseMessagesFlags(action, args[1:])
 		if err != nil {
+			return err
+		}
+		if err := runMessages(dc, channelID, flags); err != nil {
 			return fmt.Errorf("error fetching messages: %w", err)
 		}
-		cli.PrettyPrintJSON(messages)
+	case "export-guild":
+		if len(args) < 1 {
+			return fmt.Errorf("guild ID is required to export a guild")
+		}
+		guildID := args[0]
+		flags, err := parseMessagesFlags(action, args[1:])
+		if err != nil {
+			return err
+		}
+		if err := runExportGuild(dc, guildID, flags); err != nil {
+			return fmt.Errorf("error exporting guild: %w", err)
+		}
+	case "export-dms":
+		flags, err := parseMessagesFlags(action, args)
+		if err != nil {
+			return err
+		}
+		if err := runExportDMs(dc, flags); err != nil {
+			return fmt.Errorf("error exporting DMs: %w", err)
+		}
+	case "pins":
+		if len(args) < 1 {
+			return fmt.Errorf("channel ID is required to list pinned messages")
+		}
+		if err := cli.PrintPins(dc, args[0]); err != nil {
+			return fmt.Errorf("error printing pinned messages: %w", err)
+		}
+	case "search":
+		return runSearch(args)
 	default:
-		fmt.Printf("Unknown action \"%s\". Available actions: relationships, dms, create-dm, remove-dm, guilds, guild-channels, messages\n", action)
+		fmt.Printf("Unknown action \"%s\". Available actions: relationships, dms, create-dm, remove-dm, guilds, guild-channels, messages, pins, export-guild, export-dms, search\n", action)
 	}
 
 	return nil
 }
 
+// messagesFlags are the parsed flags of the messages action
+type messagesFlags struct {
+	opts         discord.IterMessagesOptions
+	format       string
+	output       string
+	incremental  bool
+	pins         bool
+	reactors     bool
+	state        string
+	embedAvatars bool
+	archive      string
+
+	csvColumns           []string
+	csvAttachmentColumns int
+
+	downloadDir         string
+	downloadEmbeds      bool
+	downloadStickers    bool
+	maxAttachmentSize   int64
+	downloadConcurrency int
+
+	dmFilter cli.DMFilter
+}
+
+// exportOptions opens the archive and downloader selected by the flags. The returned function
+// closes them and reports what was downloaded.
+func (flags messagesFlags) exportOptions(dc *discord.DiscordClient) (cli.ExportOptions, func(), error) {
+	opts := cli.ExportOptions{
+		Format:      flags.format,
+		Messages:    flags.opts,
+		Incremental: flags.incremental,
+		StatePath:   flags.state,
+		Pins:        flags.pins,
+		Writer: cli.WriterOptions{
+			EmbedAvatars:         flags.embedAvatars,
+			CSVColumns:           flags.csvColumns,
+			CSVAttachmentColumns: flags.csvAttachmentColumns,
+		},
+	}
+	if flags.reactors {
+		opts.Writer.FetchReactors = dc
+	}
+
+	if err := cli.ValidateFormat(flags.format); err != nil {
+		return opts, nil, err
+	}
+	if flags.incremental && (!flags.opts.OldestFirst || flags.opts.Around != "") {
+		return opts, nil, fmt.Errorf("--incremental cannot be combined with --newest-first or --around")
+	}
+
+	var closers []func()
+	done := func() {
+		for _, c := range closers {
+			c()
+		}
+	}
+
+	if flags.archive != "" {
+		a, err := archive.Open(flags.archive)
+		if err != nil {
+			return opts, nil, err
+		}
+		opts.Archive = a
+		closers = append(closers, func() { a.Close() })
+	}
+
+	if flags.downloadDir != "" {
+		d, err := download.NewDownloader(flags.downloadDir)
+		if err != nil {
+			done()
+			return opts, nil, err
+		}
+		d.MaxSize = flags.maxAttachmentSize
+		d.Concurrency = flags.downloadConcurrency
+		closers = append(closers, func() {
+			stats := d.Stats()
+			fmt.Fprintf(os.Stderr, "Downloaded %d files (%d bytes), reused %d, skipped %d over the size limit, %d failed\n",
+				stats.Downloaded, stats.Bytes, stats.Reused, stats.Skipped, stats.Failed)
+		})
+
+		opts.Writer.Downloader = d
+		opts.Writer.Download = cli.DownloadOptions{
+			Embeds:   flags.downloadEmbeds,
+			Stickers: flags.downloadStickers,
+		}
+	}
+
+	return opts, done, nil
+}
+
+// runMessages exports a channel's messages to stdout or a file, optionally incrementally
+// and optionally archiving them too
+func runMessages(dc *discord.DiscordClient, channelID string, flags messagesFlags) error {
+	if flags.incremental && flags.output == "" {
+		return fmt.Errorf("--incremental requires --output")
+	}
+
+	// Forum posts are threads, so forums are exported as a directory of posts
+	channel, channelErr := dc.GetChannel(context.Background(), channelID)
+	if channelErr == nil && channel.IsForum() {
+		return runExportForum(dc, channel, flags)
+	}
+	if channelErr != nil {
+		// The messages may still be readable, the channel is only described by its ID
+		channel = discord.Channel{ID: channelID}
+	}
+
+	opts, done, err := flags.exportOptions(dc)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	if opts.Archive != nil {
+		if channelErr != nil {
+			return fmt.Errorf("error archiving channel: failed to get channel: %w", channelErr)
+		}
+		if err := cli.ArchiveChannelInfo(dc, opts.Archive, channel); err != nil {
+			return fmt.Errorf("error archiving channel: %w", err)
+		}
+	}
+
+	if flags.format == cli.FormatHTML || flags.format == cli.FormatMarkdown {
+		opts.Writer.Title = cli.ChannelTitle(channel)
+	}
+	if cli.RendersContent(flags.format) {
+		opts.Writer.Resolver = cli.LoadResolver(dc, channel)
+	}
+	opts.GuildID = channel.GuildID
+
+	if flags.output != "" {
+		written, err := cli.ExportChannel(dc, channelID, flags.output, opts)
+		if flags.incremental {
+			fmt.Fprintf(os.Stderr, "Exported %d new messages to %s\n", written, flags.output)
+		}
+		return err
+	}
+
+	writerOpts := opts.Writer
+	writerOpts.Color = true
+	if flags.pins && cli.HasPinsSection(flags.format) {
+		pins, err := cli.ChannelPins(dc, channel.GuildID, channelID)
+		if err != nil {
+			return fmt.Errorf("error fetching pinned messages: %w", err)
+		}
+		writerOpts.Pins = pins
+	}
+	if writerOpts.Downloader != nil {
+		writerOpts.Download.LinkPrefix = cli.DownloadLinkPrefix("", writerOpts.Downloader.Dir)
+	}
+
+	mw, err := cli.NewMessageWriter(flags.format, os.Stdout, writerOpts)
+	if err != nil {
+		return err
+	}
+	if opts.Archive != nil {
+		mw = cli.MultiWriter(mw, archive.NewWriter(opts.Archive))
+	}
+	return cli.StreamMessages(dc, channelID, flags.opts, mw)
+}
+
+// runExportGuild exports every readable channel of a guild into a directory
+func runExportGuild(dc *discord.DiscordClient, guildID string, flags messagesFlags) error {
+	if flags.output == "" {
+		flags.output = "guild-" + guildID
+	}
+
+	opts, done, err := flags.exportOptions(dc)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	manifest, err := cli.ExportGuild(dc, guildID, flags.output, opts)
+	if manifest != nil {
+		cli.PrintManifest("guild "+cmp.Or(manifest.Guild.Name, guildID), manifest)
+	}
+	return err
+}
+
+// runExportForum exports every post of a forum or media channel into a directory
+func runExportForum(dc *discord.DiscordClient, forum discord.Channel, flags messagesFlags) error {
+	if flags.output == "" {
+		flags.output = "forum-" + forum.ID
+	}
+
+	opts, done, err := flags.exportOptions(dc)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	manifest, err := cli.ExportForum(dc, forum, flags.output, opts)
+	if manifest != nil {
+		cli.PrintManifest("forum #"+forum.Name, manifest)
+	}
+	return err
+}
+
+// runExportDMs exports every DM and group DM conversation into a directory
+func runExportDMs(dc *discord.DiscordClient, flags messagesFlags) error {
+	if flags.output == "" {
+		flags.output = "dms"
+	}
+
+	opts, done, err := flags.exportOptions(dc)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	results, err := cli.ExportDMs(dc, flags.output, flags.dmFilter, opts)
+	if err != nil {
+		return err
+	}
+	cli.PrintDMExports(results)
+	return nil
+}
+
+// parseMessagesFlags parses the flags of the messages action
+// and of the bulk export actions, where --output is a directory
+func parseMessagesFlags(action string, args []string) (messagesFlags, error) {
+	fs := flag.NewFlagSet(action, flag.ContinueOnError)
+	since := fs.String("since", "", "only messages after this date (RFC3339 or YYYY-MM-DD) or message ID")
+	until := fs.String("until", "", "only messages before this date (RFC3339, or YYYY-MM-DD to include that whole day) or message ID")
+	after := fs.String("after", "", "only messages after this message ID")
+	before := fs.String("before", "", "only messages before this message ID")
+	around := fs.String("around", "", "only the page of messages around this message ID")
+	limit := fs.Int("limit", 0, "maximum number of messages, 0 for all")
+	newestFirst := fs.Bool("newest-first", false, "output newest messages first")
+	format := fs.String("format", cli.FormatJSON, "output format: "+strings.Join(cli.Formats, ", "))
+	output := fs.String("output", "", "write to this file instead of stdout, or the directory to export into")
+	incremental := fs.Bool("incremental", false, "only fetch messages newer than the last run, appending to --output")
+	pins := fs.Bool("include-pins", false, "list pinned messages in a section at the top of HTML, Markdown and text exports")
+	reactors := fs.Bool("reactors", false, "fetch who reacted to each message, one request per reaction (slow on busy channels)")
+	embedAvatars := fs.Bool("embed-avatars", false, "inline avatar images into HTML exports so they work offline")
+	csvColumns := fs.String("csv-columns", strings.Join(cli.DefaultCSVColumns, ","), "comma separated CSV columns: "+strings.Join(cli.CSVColumnNames(), ", "))
+	csvAttachmentColumns := fs.Int("csv-attachment-columns", 0, "add this many attachment URL columns to CSV exports")
+	archivePath := fs.String("archive", "", "also store the messages in this SQLite archive, e.g. "+archive.DefaultPath)
+	downloadDir := fs.String("download-attachments", "", "download attachments into this directory and link exports to the local copies")
+	downloadEmbeds := fs.Bool("download-embeds", false, "also download embed images and thumbnails")
+	downloadStickers := fs.Bool("download-stickers", false, "also download stickers")
+	maxAttachmentSize := fs.Int64("max-attachment-size", 0, "skip downloading files larger than this many bytes, 0 for no limit")
+	downloadConcurrency := fs.Int("download-concurrency", 4, "number of files to download at once")
+	state := fs.String("state", cli.DefaultStatePath, "state file recording incremental export progress")
+	var includeUsers, excludeUsers *string
+	if action == "export-dms" {
+		includeUsers = fs.String("include-users", "", "comma separated user IDs or names; only export conversations with these users")
+		excludeUsers = fs.String("exclude-users", "", "comma separated user IDs or names; skip conversations with these users")
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return messagesFlags{}, err
+	}
+
+	switch {
+	case *since != "" && *after != "":
+		return messagesFlags{}, fmt.Errorf("--since and --after both set the lower bound, use one of them")
+	case *until != "" && *before != "":
+		return messagesFlags{}, fmt.Errorf("--until and --before both set the upper bound, use one of them")
+	case *around != "" && (*after != "" || *before != "" || *since != "" || *until != ""):
+		return messagesFlags{}, fmt.Errorf("--around can't be combined with --after, --before, --since or --until")
+	}
+
+	opts := discord.IterMessagesOptions{
+		Before:      *before,
+		After:       *after,
+		Around:      *around,
+		Limit:       *limit,
+		OldestFirst: !*newestFirst,
+	}
+
+	if *since != "" {
+		id, err := cli.ParseSinceBound(*since)
+		if err != nil {
+			return messagesFlags{}, fmt.Errorf("invalid --since: %w", err)
+		}
+		opts.After = id
+	}
+	if *until != "" {
+		id, err := cli.ParseUntilBound(*until)
+		if err != nil {
+			return messagesFlags{}, fmt.Errorf("invalid --until: %w", err)
+		}
+		opts.Before = id
+	}
+
+	if *csvAttachmentColumns < 0 {
+		return messagesFlags{}, fmt.Errorf("--csv-attachment-columns must not be negative")
+	}
+
+	columns, err := cli.ParseCSVColumns(*csvColumns)
+	if err != nil {
+		return messagesFlags{}, err
+	}
+
+	var dmFilter cli.DMFilter
+	if includeUsers != nil {
+		dmFilter.Include = splitList(*includeUsers)
+		dmFilter.Exclude = splitList(*excludeUsers)
+	}
+
+	return messagesFlags{
+		opts:                 opts,
+		format:               *format,
+		output:               *output,
+		incremental:          *incremental,
+		pins:                 *pins,
+		reactors:             *reactors,
+		state:                *state,
+		embedAvatars:         *embedAvatars,
+		archive:              *archivePath,
+		csvColumns:           columns,
+		csvAttachmentColumns: *csvAttachmentColumns,
+		downloadDir:          *downloadDir,
+		downloadEmbeds:       *downloadEmbeds,
+		downloadStickers:     *downloadStickers,
+		maxAttachmentSize:    *maxAttachmentSize,
+		downloadConcurrency:  *downloadConcurrency,
+		dmFilter:             dmFilter,
+	}, nil
+}
+
+// splitList splits a comma separated flag value, ignoring empty entries
+func splitList(value string) []string {
+	var list []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// runSearch searches the local archive; it needs no token
+func runSearch(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("search query is required, e.g. search \"deploy has:link\"")
+	}
+
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	archivePath := fs.String("archive", archive.DefaultPath, "SQLite archive to search")
+	author := fs.String("author", "", "only messages by this user ID, username or global name")
+	channel := fs.String("channel", "", "only messages in this channel ID or name")
+	guild := fs.String("guild", "", "only messages in this guild ID or name")
+	since := fs.String("since", "", "only messages after this date (RFC3339 or YYYY-MM-DD) or message ID")
+	until := fs.String("until", "", "only messages before this date (RFC3339, or YYYY-MM-DD to include that whole day) or message ID")
+	has := fs.String("has", "", "only messages that have: attachment, link")
+	limit := fs.Int("limit", 25, "maximum number of results")
+	contextSize := fs.Int("context", 0, "show this many messages before and after each result")
+
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	q := archive.SearchQuery{
+		Text:    args[0],
+		Author:  *author,
+		Channel: *channel,
+		Guild:   *guild,
+		Limit:   *limit,
+		Context: *contextSize,
+	}
+	if *has != "" {
+		q.Text += " has:" + *has
+	}
+	if err := cli.ApplySearchOperators(&q); err != nil {
+		return err
+	}
+
+	var err error
+	if q.After, err = cli.ParseSinceBound(*since); err != nil {
+		return fmt.Errorf("invalid --since: %w", err)
+	}
+	if q.Before, err = cli.ParseUntilBound(*until); err != nil {
+		return fmt.Errorf("invalid --until: %w", err)
+	}
+
+	if _, err := os.Stat(*archivePath); err != nil {
+		return fmt.Errorf("archive %s not found, export messages with --archive first", *archivePath)
+	}
+
+	a, err := archive.Open(*archivePath)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	return cli.PrintSearch(a, q)
+}
+
 func main() {
 	godotenv.Load()
 
+	// Searching the local archive doesn't need a token
+	if len(os.Args) >= 2 && os.Args[1] == "search" {
+		if err := runSearch(os.Args[2:]); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	var token string
 	var action string
 	var args []string
@@ -90,7 +526,7 @@ func main() {
 		if len(os.Args) < 2 {
 			fmt.Println("Must provide an action when using the DISCORD_TOKEN environment variable")
 			fmt.Println("Usage: ./discorder <action> [args...]")
-			fmt.Println("Available actions: relationships, dms, create-dm, remove-dm, guilds, guild-channels, messages")
+			fmt.Println("Available actions: relationships, dms, create-dm, remove-dm, guilds, guild-channels, messages, pins, export-guild, export-dms, search")
 			os.Exit(1)
 		}
 
@@ -102,7 +538,7 @@ func main() {
 			fmt.Println("Must provide a Discord Token and an action")
 			fmt.Println("Usage: ./discorder <token> <action> [args...]")
 			fmt.Println("   or: DISCORD_TOKEN=your_token ./discorder <action> [args...]")
-			fmt.Println("Available actions: relationships, dms, create-dm, remove-dm, guilds, guild-channels, messages")
+			fmt.Println("Available actions: relationships, dms, create-dm, remove-dm, guilds, guild-channels, messages, pins, export-guild, export-dms, search")
 			os.Exit(1)
 		}
 
@@ -113,5 +549,8 @@ func main() {
 
 	if err := run(token, action, args); err != nil {
 		fmt.Println(err)
+		if discord.IsUnauthorized(err) {
+			fmt.Println("The provided Discord token is invalid or has expired.")
+		}
 	}
 }
@@ -0,0 +1,113 @@
+// Package archive stores users, guilds, channels and messages in a local SQLite database,
+// so exported history can be queried across channels.
+package archive
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	_ "modernc.org/sqlite"
+)
+
+// DefaultPath is the archive database used when none is given
+const DefaultPath = "discorder.db"
+
+// Archive is a SQLite database of archived Discord data.
+// Rows are keyed by snowflake ID, and every write is an upsert, so archiving the same
+// messages again updates them (e.g. after an edit) instead of duplicating them.
+type Archive struct {
+	db *sql.DB
+}
+
+// Open opens (creating if necessary) the archive database at path and migrates it to the latest schema.
+func Open(path string) (*Archive, error) {
+	db, err := sql.Open("sqlite", dsn(path))
+	if err != nil {
+		return nil, fmt.Errorf("error opening archive: %w", err)
+	}
+
+	// SQLite only allows one writer, so there is no point in more connections
+	db.SetMaxOpenConns(1)
+
+	a := &Archive{db: db}
+	if err := a.migrate(context.Background()); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return a, nil
+}
+
+// dsn returns the SQLite URI of the database at path, with the pragmas every connection sets.
+// SQLite decodes %XX escapes in URIs, so characters that would end the path are escaped.
+func dsn(path string) string {
+	u := url.URL{
+		Scheme:   "file",
+		Opaque:   uriPathEscaper.Replace(path),
+		RawQuery: "_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)",
+	}
+	return u.String()
+}
+
+var uriPathEscaper = strings.NewReplacer("%", "%25", "?", "%3F", "#", "%23")
+
+// Close closes the database.
+func (a *Archive) Close() error {
+	return a.db.Close()
+}
+
+// DB returns the underlying database for ad-hoc queries.
+func (a *Archive) DB() *sql.DB {
+	return a.db
+}
+
+// migrate applies every migration newer than the database's user_version.
+func (a *Archive) migrate(ctx context.Context) error {
+	var version int
+	if err := a.db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("error reading archive version: %w", err)
+	}
+
+	for i := version; i < len(migrations); i++ {
+		tx, err := a.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("error migrating archive to version %d: %w", i+1, err)
+		}
+		// PRAGMA doesn't support parameters
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("error migrating archive to version %d: %w", i+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("error migrating archive to version %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// snowflake converts a snowflake ID to its integer key, or nil for an empty ID.
+func snowflake(id string) any {
+	if id == "" {
+		return nil
+	}
+	v, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil
+	}
+	return int64(v)
+}
+
+// nullString returns nil for empty strings so they are stored as NULL.
+func nullString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,30 @@
+package archive
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/CaptainFallaway/Discorder/internal/discord"
+)
+
+func TestOpenPathWithURICharacters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "what?#100%.db")
+	a, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	if err := a.SaveGuilds(context.Background(), discord.Guild{ID: "10", Name: "Guild"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("database not created at its path: %v", err)
+	}
+	// The pragmas still apply
+	if n := countRows(t, a, `PRAGMA foreign_keys`); n != 1 {
+		t.Errorf("foreign_keys = %d, want 1", n)
+	}
+}
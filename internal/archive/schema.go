@@ -0,0 +1,125 @@
+package archive
+
+// migrations are applied in order; the database's user_version records how many have run.
+// Never edit a released migration, append a new one instead.
+var migrations = []string{
+	// 1: initial schema
+	`
+CREATE TABLE users (
+	id          INTEGER PRIMARY KEY,
+	username    TEXT NOT NULL,
+	global_name TEXT,
+	avatar      TEXT,
+	updated_at  TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now'))
+);
+
+CREATE TABLE guilds (
+	id          INTEGER PRIMARY KEY,
+	name        TEXT NOT NULL,
+	owner       INTEGER NOT NULL DEFAULT 0,
+	nsfw_level  INTEGER NOT NULL DEFAULT 0,
+	description TEXT,
+	updated_at  TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now'))
+);
+
+CREATE TABLE channels (
+	id         INTEGER PRIMARY KEY,
+	guild_id   INTEGER,
+	parent_id  INTEGER,
+	type       INTEGER NOT NULL,
+	name       TEXT,
+	topic      TEXT,
+	nsfw       INTEGER NOT NULL DEFAULT 0,
+	updated_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now'))
+);
+CREATE INDEX channels_guild ON channels (guild_id);
+
+CREATE TABLE channel_recipients (
+	channel_id INTEGER NOT NULL REFERENCES channels (id) ON DELETE CASCADE,
+	user_id    INTEGER NOT NULL,
+	PRIMARY KEY (channel_id, user_id)
+);
+
+CREATE TABLE messages (
+	id               INTEGER PRIMARY KEY,
+	channel_id       INTEGER NOT NULL,
+	guild_id         INTEGER,
+	author_id        INTEGER,
+	type             INTEGER NOT NULL,
+	content          TEXT NOT NULL,
+	timestamp        TEXT NOT NULL,
+	edited_timestamp TEXT,
+	pinned           INTEGER NOT NULL DEFAULT 0,
+	flags            INTEGER NOT NULL DEFAULT 0,
+	reply_to_id      INTEGER,
+	raw              TEXT NOT NULL,
+	archived_at      TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now'))
+);
+CREATE INDEX messages_channel ON messages (channel_id, id);
+CREATE INDEX messages_author ON messages (author_id);
+
+CREATE TABLE attachments (
+	id           INTEGER PRIMARY KEY,
+	message_id   INTEGER NOT NULL REFERENCES messages (id) ON DELETE CASCADE,
+	filename     TEXT NOT NULL,
+	content_type TEXT,
+	size         INTEGER NOT NULL DEFAULT 0,
+	url          TEXT NOT NULL,
+	proxy_url    TEXT,
+	width        INTEGER,
+	height       INTEGER
+);
+CREATE INDEX attachments_message ON attachments (message_id);
+
+CREATE TABLE embeds (
+	message_id  INTEGER NOT NULL REFERENCES messages (id) ON DELETE CASCADE,
+	position    INTEGER NOT NULL,
+	type        TEXT,
+	title       TEXT,
+	description TEXT,
+	url         TEXT,
+	raw         TEXT NOT NULL,
+	PRIMARY KEY (message_id, position)
+);
+
+CREATE TABLE reactions (
+	message_id   INTEGER NOT NULL REFERENCES messages (id) ON DELETE CASCADE,
+	emoji_id     INTEGER NOT NULL DEFAULT 0,
+	emoji_name   TEXT NOT NULL,
+	count        INTEGER NOT NULL,
+	normal_count INTEGER NOT NULL DEFAULT 0,
+	burst_count  INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (message_id, emoji_id, emoji_name)
+);
+
+CREATE TABLE mentions (
+	message_id INTEGER NOT NULL REFERENCES messages (id) ON DELETE CASCADE,
+	user_id    INTEGER NOT NULL,
+	PRIMARY KEY (message_id, user_id)
+);
+`,
+	// 2: full-text search over message content, kept in sync by triggers
+	`
+CREATE VIRTUAL TABLE messages_fts USING fts5 (
+	content,
+	content = 'messages',
+	content_rowid = 'id',
+	tokenize = 'unicode61 remove_diacritics 2'
+);
+
+CREATE TRIGGER messages_fts_insert AFTER INSERT ON messages BEGIN
+	INSERT INTO messages_fts (rowid, content) VALUES (new.id, new.content);
+END;
+
+CREATE TRIGGER messages_fts_delete AFTER DELETE ON messages BEGIN
+	INSERT INTO messages_fts (messages_fts, rowid, content) VALUES ('delete', old.id, old.content);
+END;
+
+CREATE TRIGGER messages_fts_update AFTER UPDATE OF content ON messages BEGIN
+	INSERT INTO messages_fts (messages_fts, rowid, content) VALUES ('delete', old.id, old.content);
+	INSERT INTO messages_fts (rowid, content) VALUES (new.id, new.content);
+END;
+
+INSERT INTO messages_fts (messages_fts) VALUES ('rebuild');
+`,
+}
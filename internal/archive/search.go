@@ -0,0 +1,216 @@
+package archive
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/CaptainFallaway/Discorder/internal/discord"
+)
+
+// SearchQuery selects archived messages. Empty fields don't filter.
+type SearchQuery struct {
+	// Text is matched against message content with full-text search; results are ranked by relevance
+	Text string
+	// Author matches a user ID, username or global name
+	Author string
+	// Channel matches a channel ID or name
+	Channel string
+	// Guild matches a guild ID or name
+	Guild string
+	// After and Before bound the message IDs (use discord.SnowflakeFromTime for dates)
+	After  string
+	Before string
+	// HasAttachment and HasLink only include messages with attachments or links
+	HasAttachment bool
+	HasLink       bool
+	// Limit caps the number of results, 0 means 25
+	Limit int
+	// Context includes this many messages before and after each result
+	Context int
+}
+
+// SearchResult is an archived message matching a search.
+type SearchResult struct {
+	ID          string
+	ChannelID   string
+	GuildID     string
+	ChannelName string
+	GuildName   string
+	Author      discord.User
+	Content     string
+	Timestamp   string
+	Attachments int
+
+	// Before and After are the surrounding messages of the channel, oldest first
+	Before []SearchResult
+	After  []SearchResult
+}
+
+// JumpURL returns the link that opens the message in the Discord client.
+func (r SearchResult) JumpURL() string {
+	return discord.JumpURL(r.GuildID, r.ChannelID, r.ID)
+}
+
+// resultColumns are selected for every search result, in scanResult order
+const resultColumns = `
+	CAST(m.id AS TEXT), CAST(m.channel_id AS TEXT), coalesce(CAST(coalesce(m.guild_id, c.guild_id) AS TEXT), ''),
+	coalesce(c.name, ''), coalesce(g.name, ''),
+	coalesce(CAST(u.id AS TEXT), ''), coalesce(u.username, ''), coalesce(u.global_name, ''), coalesce(u.avatar, ''),
+	m.content, m.timestamp,
+	(SELECT count(*) FROM attachments a WHERE a.message_id = m.id)`
+
+// resultJoins joins the tables resultColumns reads from
+const resultJoins = `
+	LEFT JOIN channels c ON c.id = m.channel_id
+	LEFT JOIN guilds g ON g.id = coalesce(m.guild_id, c.guild_id)
+	LEFT JOIN users u ON u.id = m.author_id`
+
+// Search finds archived messages matching q, most relevant first when searching text
+// and newest first otherwise.
+func (a *Archive) Search(ctx context.Context, q SearchQuery) ([]SearchResult, error) {
+	var (
+		where []string
+		args  []any
+		from  = "messages m"
+		order = "m.id DESC"
+	)
+
+	if match := ftsQuery(q.Text); match != "" {
+		from = "messages_fts f JOIN messages m ON m.id = f.rowid"
+		where = append(where, "messages_fts MATCH ?")
+		args = append(args, match)
+		order = "bm25(messages_fts), m.id DESC"
+	}
+
+	if q.Author != "" {
+		where = append(where, "(m.author_id = ? OR u.username = ? COLLATE NOCASE OR u.global_name = ? COLLATE NOCASE)")
+		args = append(args, snowflake(q.Author), q.Author, q.Author)
+	}
+	if q.Channel != "" {
+		where = append(where, "(m.channel_id = ? OR c.name = ? COLLATE NOCASE)")
+		args = append(args, snowflake(q.Channel), strings.TrimPrefix(q.Channel, "#"))
+	}
+	if q.Guild != "" {
+		where = append(where, "(coalesce(m.guild_id, c.guild_id) = ? OR g.name = ? COLLATE NOCASE)")
+		args = append(args, snowflake(q.Guild), q.Guild)
+	}
+	if id := snowflake(q.After); id != nil {
+		where = append(where, "m.id > ?")
+		args = append(args, id)
+	}
+	if id := snowflake(q.Before); id != nil {
+		where = append(where, "m.id < ?")
+		args = append(args, id)
+	}
+	if q.HasAttachment {
+		where = append(where, "EXISTS (SELECT 1 FROM attachments a WHERE a.message_id = m.id)")
+	}
+	if q.HasLink {
+		where = append(where, "(m.content LIKE '%http://%' OR m.content LIKE '%https://%')")
+	}
+
+	limit := q.Limit
+	if limit <= 0 {
+		limit = 25
+	}
+
+	query := "SELECT " + resultColumns + " FROM " + from + resultJoins
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY " + order + " LIMIT ?"
+	args = append(args, limit)
+
+	results, err := a.queryResults(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error searching archive: %w", err)
+	}
+
+	if q.Context > 0 {
+		for i := range results {
+			if err := a.loadContext(ctx, &results[i], q.Context); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// loadContext fills in the n messages before and after a result in its channel
+func (a *Archive) loadContext(ctx context.Context, r *SearchResult, n int) error {
+	base := "SELECT " + resultColumns + " FROM messages m" + resultJoins + " WHERE m.channel_id = ? AND "
+
+	before, err := a.queryResults(ctx, base+"m.id < ? ORDER BY m.id DESC LIMIT ?", snowflake(r.ChannelID), snowflake(r.ID), n)
+	if err != nil {
+		return fmt.Errorf("error loading search context: %w", err)
+	}
+	// Fetched newest first, shown oldest first
+	for i, j := 0, len(before)-1; i < j; i, j = i+1, j-1 {
+		before[i], before[j] = before[j], before[i]
+	}
+
+	after, err := a.queryResults(ctx, base+"m.id > ? ORDER BY m.id ASC LIMIT ?", snowflake(r.ChannelID), snowflake(r.ID), n)
+	if err != nil {
+		return fmt.Errorf("error loading search context: %w", err)
+	}
+
+	r.Before, r.After = before, after
+	return nil
+}
+
+func (a *Archive) queryResults(ctx context.Context, query string, args ...any) ([]SearchResult, error) {
+	rows, err := a.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		r, err := scanResult(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+func scanResult(rows *sql.Rows) (SearchResult, error) {
+	var r SearchResult
+	err := rows.Scan(
+		&r.ID, &r.ChannelID, &r.GuildID,
+		&r.ChannelName, &r.GuildName,
+		&r.Author.ID, &r.Author.Username, &r.Author.GlobalName, &r.Author.Avatar,
+		&r.Content, &r.Timestamp,
+		&r.Attachments,
+	)
+	return r, err
+}
+
+// ftsQuery turns free text into an FTS5 query matching every word.
+// Words are quoted so punctuation can't be mistaken for query syntax; a trailing * keeps prefix matching.
+func ftsQuery(text string) string {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return unicode.IsSpace(r)
+	})
+
+	terms := make([]string, 0, len(words))
+	for _, w := range words {
+		prefix := strings.HasSuffix(w, "*")
+		w = strings.Trim(w, "*")
+		if w == "" {
+			continue
+		}
+		term := `"` + strings.ReplaceAll(w, `"`, `""`) + `"`
+		if prefix {
+			term += "*"
+		}
+		terms = append(terms, term)
+	}
+	return strings.Join(terms, " ")
+}
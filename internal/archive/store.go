@@ -0,0 +1,256 @@
+package archive
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/CaptainFallaway/Discorder/internal/discord"
+)
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// SaveUsers upserts users.
+func (a *Archive) SaveUsers(ctx context.Context, users ...discord.User) error {
+	return a.inTx(ctx, func(tx *sql.Tx) error {
+		for _, u := range users {
+			if err := saveUser(ctx, tx, u); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SaveGuilds upserts guilds.
+func (a *Archive) SaveGuilds(ctx context.Context, guilds ...discord.Guild) error {
+	return a.inTx(ctx, func(tx *sql.Tx) error {
+		for _, g := range guilds {
+			_, err := tx.ExecContext(ctx, `
+INSERT INTO guilds (id, name, owner, nsfw_level, description)
+VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (id) DO UPDATE SET
+	name = excluded.name,
+	owner = excluded.owner,
+	nsfw_level = excluded.nsfw_level,
+	description = excluded.description,
+	updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now')`,
+				snowflake(g.ID), g.Name, g.Owner, g.NSFWLevel, nullString(g.Description))
+			if err != nil {
+				return fmt.Errorf("error saving guild %s: %w", g.ID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// SaveChannels upserts channels and their DM recipients.
+func (a *Archive) SaveChannels(ctx context.Context, channels ...discord.Channel) error {
+	return a.inTx(ctx, func(tx *sql.Tx) error {
+		for _, c := range channels {
+			_, err := tx.ExecContext(ctx, `
+INSERT INTO channels (id, guild_id, parent_id, type, name, topic, nsfw)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (id) DO UPDATE SET
+	guild_id = coalesce(excluded.guild_id, channels.guild_id),
+	parent_id = excluded.parent_id,
+	type = excluded.type,
+	name = excluded.name,
+	topic = excluded.topic,
+	nsfw = excluded.nsfw,
+	updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now')`,
+				snowflake(c.ID), snowflake(c.GuildID), snowflake(c.ParentID), c.Type, nullString(c.Name), nullString(c.Topic), c.NSFW)
+			if err != nil {
+				return fmt.Errorf("error saving channel %s: %w", c.ID, err)
+			}
+
+			if len(c.Recipients) == 0 {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, `DELETE FROM channel_recipients WHERE channel_id = ?`, snowflake(c.ID)); err != nil {
+				return err
+			}
+			for _, u := range c.Recipients {
+				userID := snowflake(u.ID)
+				if userID == nil {
+					continue
+				}
+				if err := saveUser(ctx, tx, u); err != nil {
+					return err
+				}
+				if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO channel_recipients (channel_id, user_id) VALUES (?, ?)`, snowflake(c.ID), userID); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// SaveMessages upserts messages with their authors, mentions, attachments, embeds and reactions.
+// Messages that are already archived are updated and their child rows replaced.
+func (a *Archive) SaveMessages(ctx context.Context, messages ...discord.Message) error {
+	return a.inTx(ctx, func(tx *sql.Tx) error {
+		for _, m := range messages {
+			// Replies carry a copy of the message they reply to, which is worth keeping too.
+			// The copy is partial (no reactions, for one), so it never replaces an archived message.
+			if ref := m.ReferencedMessage; ref != nil && ref.ID != "" {
+				if err := saveMessage(ctx, tx, *ref, false); err != nil {
+					return err
+				}
+			}
+			if err := saveMessage(ctx, tx, m, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func saveUser(ctx context.Context, ex execer, u discord.User) error {
+	if snowflake(u.ID) == nil {
+		return nil
+	}
+	_, err := ex.ExecContext(ctx, `
+INSERT INTO users (id, username, global_name, avatar)
+VALUES (?, ?, ?, ?)
+ON CONFLICT (id) DO UPDATE SET
+	username = excluded.username,
+	global_name = excluded.global_name,
+	avatar = excluded.avatar,
+	updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now')`,
+		snowflake(u.ID), u.Username, nullString(u.GlobalName), nullString(u.Avatar))
+	if err != nil {
+		return fmt.Errorf("error saving user %s: %w", u.ID, err)
+	}
+	return nil
+}
+
+// saveMessage inserts a message and its child rows. If the message is already archived,
+// it is updated and its child rows replaced, or left as it is unless replace is set.
+func saveMessage(ctx context.Context, ex execer, m discord.Message, replace bool) error {
+	if err := saveUser(ctx, ex, m.Author); err != nil {
+		return err
+	}
+
+	raw, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("error encoding message %s: %w", m.ID, err)
+	}
+
+	var replyTo any
+	if m.MessageReference != nil && m.Type == discord.MessageReply {
+		replyTo = snowflake(m.MessageReference.MessageID)
+	}
+
+	onConflict := `DO UPDATE SET
+	guild_id = coalesce(excluded.guild_id, messages.guild_id),
+	content = excluded.content,
+	edited_timestamp = excluded.edited_timestamp,
+	pinned = excluded.pinned,
+	flags = excluded.flags,
+	raw = excluded.raw,
+	archived_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now')`
+	if !replace {
+		onConflict = "DO NOTHING"
+	}
+
+	res, err := ex.ExecContext(ctx, `
+INSERT INTO messages (id, channel_id, guild_id, author_id, type, content, timestamp, edited_timestamp, pinned, flags, reply_to_id, raw)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (id) `+onConflict,
+		snowflake(m.ID), snowflake(m.ChannelID), snowflake(m.GuildID), snowflake(m.Author.ID), m.Type, m.Content,
+		m.Timestamp, nullString(m.EditedTimestamp), m.Pinned, m.Flags, replyTo, string(raw))
+	if err != nil {
+		return fmt.Errorf("error saving message %s: %w", m.ID, err)
+	}
+	if !replace {
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			// Already archived, its child rows are left alone
+			return nil
+		}
+	}
+
+	id := snowflake(m.ID)
+	for _, table := range []string{"attachments", "embeds", "reactions", "mentions"} {
+		if _, err := ex.ExecContext(ctx, "DELETE FROM "+table+" WHERE message_id = ?", id); err != nil {
+			return fmt.Errorf("error replacing %s of message %s: %w", table, m.ID, err)
+		}
+	}
+
+	for _, at := range m.Attachments {
+		_, err := ex.ExecContext(ctx, `
+INSERT OR REPLACE INTO attachments (id, message_id, filename, content_type, size, url, proxy_url, width, height)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			snowflake(at.ID), id, at.Filename, nullString(at.ContentType), at.Size, at.URL, nullString(at.ProxyURL), at.Width, at.Height)
+		if err != nil {
+			return fmt.Errorf("error saving attachment %s: %w", at.ID, err)
+		}
+	}
+
+	for i, e := range m.Embeds {
+		raw, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		_, err = ex.ExecContext(ctx, `
+INSERT INTO embeds (message_id, position, type, title, description, url, raw)
+VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			id, i, nullString(e.Type), nullString(e.Title), nullString(e.Description), nullString(e.URL), string(raw))
+		if err != nil {
+			return fmt.Errorf("error saving embed of message %s: %w", m.ID, err)
+		}
+	}
+
+	for _, r := range m.Reactions {
+		emojiID := snowflake(r.Emoji.ID)
+		if emojiID == nil {
+			emojiID = 0
+		}
+		_, err := ex.ExecContext(ctx, `
+INSERT OR REPLACE INTO reactions (message_id, emoji_id, emoji_name, count, normal_count, burst_count)
+VALUES (?, ?, ?, ?, ?, ?)`,
+			id, emojiID, r.Emoji.Name, r.Count, r.CountDetails.Normal, r.CountDetails.Burst)
+		if err != nil {
+			return fmt.Errorf("error saving reaction of message %s: %w", m.ID, err)
+		}
+	}
+
+	for _, u := range m.Mentions {
+		// A user without a valid ID can't be linked, and would fail the whole save
+		userID := snowflake(u.ID)
+		if userID == nil {
+			continue
+		}
+		if err := saveUser(ctx, ex, u); err != nil {
+			return err
+		}
+		if _, err := ex.ExecContext(ctx, `INSERT OR IGNORE INTO mentions (message_id, user_id) VALUES (?, ?)`, id, userID); err != nil {
+			return fmt.Errorf("error saving mention of message %s: %w", m.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// inTx runs fn in a transaction, committing if it succeeds and rolling back otherwise.
+func (a *Archive) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting archive transaction: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing archive transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package archive
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/CaptainFallaway/Discorder/internal/discord"
+)
+
+func openTestArchive(t *testing.T) *Archive {
+	t.Helper()
+	a, err := Open(filepath.Join(t.TempDir(), "archive.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { a.Close() })
+	return a
+}
+
+func countRows(t *testing.T, a *Archive, query string, args ...any) int {
+	t.Helper()
+	var n int
+	if err := a.DB().QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestSaveReplyKeepsArchivedParent(t *testing.T) {
+	ctx := context.Background()
+	a := openTestArchive(t)
+
+	alice := discord.User{ID: "1", Username: "alice"}
+	parent := discord.Message{
+		ID: "1001", ChannelID: "100", Author: alice, Content: "poll: cats or dogs?", Timestamp: "2025-01-02T12:00:00Z",
+		Reactions: []discord.Reaction{{Count: 3, Emoji: discord.Emoji{Name: "🐱"}}},
+		Mentions:  []discord.User{{ID: "2", Username: "bob"}},
+	}
+	if err := a.SaveMessages(ctx, parent); err != nil {
+		t.Fatal(err)
+	}
+
+	// The copy embedded in a reply has no reactions
+	ref := parent
+	ref.Reactions = nil
+	ref.Mentions = nil
+	ref.Content = "poll: cats or dogs? (partial)"
+	reply := discord.Message{
+		ID: "1002", ChannelID: "100", Author: alice, Content: "cats", Timestamp: "2025-01-02T12:01:00Z",
+		Type: discord.MessageReply, MessageReference: &discord.MessageReference{MessageID: parent.ID}, ReferencedMessage: &ref,
+	}
+	if err := a.SaveMessages(ctx, reply); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := countRows(t, a, `SELECT count(*) FROM reactions WHERE message_id = 1001`); n != 1 {
+		t.Errorf("parent has %d reactions, want 1", n)
+	}
+	if n := countRows(t, a, `SELECT count(*) FROM mentions WHERE message_id = 1001`); n != 1 {
+		t.Errorf("parent has %d mentions, want 1", n)
+	}
+	var content string
+	if err := a.DB().QueryRow(`SELECT content FROM messages WHERE id = 1001`).Scan(&content); err != nil {
+		t.Fatal(err)
+	}
+	if content != parent.Content {
+		t.Errorf("parent content = %q, want %q", content, parent.Content)
+	}
+}
+
+func TestSaveReplyArchivesUnknownParent(t *testing.T) {
+	ctx := context.Background()
+	a := openTestArchive(t)
+
+	alice := discord.User{ID: "1", Username: "alice"}
+	ref := discord.Message{ID: "1001", ChannelID: "100", Author: alice, Content: "original", Timestamp: "2025-01-02T12:00:00Z"}
+	reply := discord.Message{
+		ID: "1002", ChannelID: "100", Author: alice, Content: "reply", Timestamp: "2025-01-02T12:01:00Z",
+		Type: discord.MessageReply, MessageReference: &discord.MessageReference{MessageID: ref.ID}, ReferencedMessage: &ref,
+	}
+	if err := a.SaveMessages(ctx, reply); err != nil {
+		t.Fatal(err)
+	}
+	if n := countRows(t, a, `SELECT count(*) FROM messages`); n != 2 {
+		t.Errorf("archived %d messages, want 2", n)
+	}
+
+	// The full message replaces the copy
+	full := ref
+	full.Reactions = []discord.Reaction{{Count: 1, Emoji: discord.Emoji{Name: "👍"}}}
+	if err := a.SaveMessages(ctx, full); err != nil {
+		t.Fatal(err)
+	}
+	if n := countRows(t, a, `SELECT count(*) FROM reactions WHERE message_id = 1001`); n != 1 {
+		t.Errorf("parent has %d reactions, want 1", n)
+	}
+}
+
+func TestSaveSkipsUsersWithoutID(t *testing.T) {
+	ctx := context.Background()
+	a := openTestArchive(t)
+
+	bob := discord.User{ID: "2", Username: "bob"}
+	dm := discord.Channel{ID: "100", Type: discord.ChannelDM, Recipients: []discord.User{{Username: "ghost"}, bob}}
+	if err := a.SaveChannels(ctx, dm); err != nil {
+		t.Fatal(err)
+	}
+	m := discord.Message{
+		ID: "1001", ChannelID: "100", Author: bob, Content: "hi @ghost", Timestamp: "2025-01-02T12:00:00Z",
+		Mentions: []discord.User{{Username: "ghost"}, {ID: "not-a-snowflake", Username: "broken"}, bob},
+	}
+	if err := a.SaveMessages(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := countRows(t, a, `SELECT count(*) FROM messages`); n != 1 {
+		t.Errorf("archived %d messages, want 1", n)
+	}
+	if n := countRows(t, a, `SELECT count(*) FROM mentions WHERE message_id = 1001`); n != 1 {
+		t.Errorf("message has %d mentions, want only bob", n)
+	}
+	if n := countRows(t, a, `SELECT count(*) FROM channel_recipients WHERE channel_id = 100`); n != 1 {
+		t.Errorf("channel has %d recipients, want only bob", n)
+	}
+	if n := countRows(t, a, `SELECT count(*) FROM users`); n != 1 {
+		t.Errorf("archived %d users, want only bob", n)
+	}
+}
@@ -0,0 +1,54 @@
+package archive
+
+import (
+	"context"
+
+	"github.com/CaptainFallaway/Discorder/internal/discord"
+)
+
+// writerBatchSize is how many messages are saved per transaction, one API page
+const writerBatchSize = 100
+
+// Writer archives messages as they are exported. It satisfies cli.MessageWriter.
+type Writer struct {
+	archive *Archive
+	batch   []discord.Message
+	count   int
+}
+
+// NewWriter returns a Writer saving messages to the archive in batches.
+func NewWriter(a *Archive) *Writer {
+	return &Writer{archive: a, batch: make([]discord.Message, 0, writerBatchSize)}
+}
+
+// WriteMessage queues a message, saving the batch once it is full.
+func (w *Writer) WriteMessage(message discord.Message) error {
+	w.batch = append(w.batch, message)
+	if len(w.batch) >= writerBatchSize {
+		return w.Flush()
+	}
+	return nil
+}
+
+// Close saves any queued messages. It does not close the archive.
+func (w *Writer) Close() error {
+	return w.Flush()
+}
+
+// Count returns how many messages have been saved.
+func (w *Writer) Count() int {
+	return w.count
+}
+
+// Flush saves any queued messages now.
+func (w *Writer) Flush() error {
+	if len(w.batch) == 0 {
+		return nil
+	}
+	if err := w.archive.SaveMessages(context.Background(), w.batch...); err != nil {
+		return err
+	}
+	w.count += len(w.batch)
+	w.batch = w.batch[:0]
+	return nil
+}
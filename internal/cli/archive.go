@@ -0,0 +1,34 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/CaptainFallaway/Discorder/internal/archive"
+	"github.com/CaptainFallaway/Discorder/internal/discord"
+)
+
+// ArchiveChannelInfo saves a channel, its recipients and its guild to the archive,
+// so archived messages can be searched by channel and guild name.
+func ArchiveChannelInfo(dc *discord.DiscordClient, a *archive.Archive, channel discord.Channel) error {
+	ctx := context.Background()
+
+	if err := a.SaveChannels(ctx, channel); err != nil {
+		return err
+	}
+
+	if channel.GuildID == "" {
+		return nil
+	}
+
+	guilds, err := dc.GetUserGuilds(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get guilds: %w", err)
+	}
+	for _, g := range guilds {
+		if g.ID == channel.GuildID {
+			return a.SaveGuilds(ctx, g)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package cli
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/CaptainFallaway/Discorder/internal/discord"
+)
+
+// FormatCSV renders one spreadsheet row per message
+const FormatCSV = "csv"
+
+// csvColumns maps each CSV column name to how it is read from a message
+var csvColumns = map[string]func(discord.Message) string{
+	"id":               func(m discord.Message) string { return m.ID },
+	"channel_id":       func(m discord.Message) string { return m.ChannelID },
+	"type":             func(m discord.Message) string { return strconv.Itoa(m.Type) },
+	"timestamp":        func(m discord.Message) string { return m.Timestamp },
+	"edited_timestamp": func(m discord.Message) string { return m.EditedTimestamp },
+	"author_id":        func(m discord.Message) string { return m.Author.ID },
+	"author_name":      func(m discord.Message) string { return m.Author.GetName() },
+	"content":          func(m discord.Message) string { return m.Content },
+	"attachment_count": func(m discord.Message) string { return strconv.Itoa(len(m.Attachments)) },
+	"reply_to_id": func(m discord.Message) string {
+		if m.MessageReference != nil && m.Type == discord.MessageReply {
+			return m.MessageReference.MessageID
+		}
+		return ""
+	},
+	"reaction_count": func(m discord.Message) string {
+		total := 0
+		for _, r := range m.Reactions {
+			total += r.Count
+		}
+		return strconv.Itoa(total)
+	},
+	"reactions": func(m discord.Message) string {
+		parts := make([]string, 0, len(m.Reactions))
+		for _, r := range m.Reactions {
+			parts = append(parts, fmt.Sprintf("%s:%d", r.Emoji.Name, r.Count))
+		}
+		return strings.Join(parts, " ")
+	},
+}
+
+// DefaultCSVColumns are the columns written when none are configured
+var DefaultCSVColumns = []string{"id", "timestamp", "author_id", "author_name", "content", "attachment_count", "reply_to_id", "reaction_count"}
+
+// CSVColumnNames returns every supported CSV column name, sorted
+func CSVColumnNames() []string {
+	names := make([]string, 0, len(csvColumns))
+	for name := range csvColumns {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// ParseCSVColumns parses a comma separated list of CSV column names
+func ParseCSVColumns(list string) ([]string, error) {
+	if strings.TrimSpace(list) == "" {
+		return nil, nil
+	}
+
+	columns := strings.Split(list, ",")
+	for i, c := range columns {
+		c = strings.TrimSpace(c)
+		if _, ok := csvColumns[c]; !ok {
+			return nil, fmt.Errorf("unknown CSV column %q, available columns: %s", c, strings.Join(CSVColumnNames(), ", "))
+		}
+		columns[i] = c
+	}
+	return columns, nil
+}
+
+// csvWriter writes one CSV record per message.
+type csvWriter struct {
+	w                 *csv.Writer
+	columns           []string
+	attachmentColumns int
+	wroteHeader       bool
+	// truncated counts messages with more attachments than attachment columns
+	truncated int
+}
+
+// NewCSVWriter returns a MessageWriter producing CSV with a header row.
+// opts.CSVColumns selects the columns, and opts.CSVAttachmentColumns adds that many
+// attachment URL columns after them. Cells that spreadsheets would run as formulas are
+// prefixed with a quote.
+func NewCSVWriter(w io.Writer, opts WriterOptions) MessageWriter {
+	columns := opts.CSVColumns
+	if len(columns) == 0 {
+		columns = DefaultCSVColumns
+	}
+	return &csvWriter{w: csv.NewWriter(w), columns: columns, attachmentColumns: max(opts.CSVAttachmentColumns, 0)}
+}
+
+func (cw *csvWriter) WriteMessage(message discord.Message) error {
+	if err := cw.writeHeader(); err != nil {
+		return err
+	}
+
+	record := make([]string, 0, len(cw.columns)+cw.attachmentColumns)
+	for _, c := range cw.columns {
+		record = append(record, csvCell(csvColumns[c](message)))
+	}
+	for i := range cw.attachmentColumns {
+		url := ""
+		if i < len(message.Attachments) {
+			url = message.Attachments[i].URL
+		}
+		record = append(record, csvCell(url))
+	}
+	if cw.attachmentColumns > 0 && len(message.Attachments) > cw.attachmentColumns {
+		cw.truncated++
+	}
+
+	if err := cw.w.Write(record); err != nil {
+		return err
+	}
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+func (cw *csvWriter) Close() error {
+	if err := cw.writeHeader(); err != nil {
+		return err
+	}
+	if cw.truncated > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: %d messages had more attachments than the %d attachment columns, the rest were left out\n", cw.truncated, cw.attachmentColumns)
+	}
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+func (cw *csvWriter) writeHeader() error {
+	if cw.wroteHeader {
+		return nil
+	}
+	cw.wroteHeader = true
+
+	header := slices.Clone(cw.columns)
+	for i := range cw.attachmentColumns {
+		header = append(header, fmt.Sprintf("attachment_url_%d", i+1))
+	}
+	return cw.w.Write(header)
+}
+
+// csvCell neutralises a cell that spreadsheets would evaluate as a formula by prefixing a quote
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/csv"
+	"slices"
+	"testing"
+
+	"github.com/CaptainFallaway/Discorder/internal/discord"
+)
+
+func TestCSVWriter(t *testing.T) {
+	messages := []discord.Message{
+		{ID: "1", Content: "=HYPERLINK(\"http://evil\")", Attachments: []discord.Attachment{{URL: "a1"}, {URL: "a2"}, {URL: "a3"}}},
+		{ID: "2", Content: "-1+2"},
+		{ID: "3", Content: "plain @ text"},
+	}
+
+	var buf bytes.Buffer
+	mw := NewCSVWriter(&buf, WriterOptions{CSVColumns: []string{"id", "content"}, CSVAttachmentColumns: 2})
+	for _, m := range messages {
+		if err := mw.WriteMessage(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"id", "content", "attachment_url_1", "attachment_url_2"},
+		{"1", "'=HYPERLINK(\"http://evil\")", "a1", "a2"},
+		{"2", "'-1+2", "", ""},
+		{"3", "plain @ text", "", ""},
+	}
+	if !slices.EqualFunc(records, want, slices.Equal) {
+		t.Errorf("records = %q\nwant %q", records, want)
+	}
+	if n := mw.(*csvWriter).truncated; n != 1 {
+		t.Errorf("truncated = %d, want 1", n)
+	}
+}
+
+func TestCSVWriterNegativeAttachmentColumns(t *testing.T) {
+	var buf bytes.Buffer
+	mw := NewCSVWriter(&buf, WriterOptions{CSVColumns: []string{"id"}, CSVAttachmentColumns: -100})
+	if err := mw.WriteMessage(discord.Message{ID: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "id\n1\n" {
+		t.Errorf("output = %q", got)
+	}
+}
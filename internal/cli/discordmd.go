@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Discord markdown segment kinds
+const (
+	segmentText = iota
+	segmentInlineCode
+	segmentCodeBlock
+)
+
+// mdSegment is a run of message content that is either plain text or code.
+// Formatting only applies to text segments; code is kept verbatim.
+type mdSegment struct {
+	kind int
+	lang string // Language of a code block, if given
+	text string
+}
+
+var (
+	codeBlockRegex  = regexp.MustCompile("(?s)```(?:([A-Za-z0-9_+#.-]+)\n)?(.*?)```")
+	inlineCodeRegex = regexp.MustCompile("``([^`]+?)``|`([^`\n]+?)`")
+)
+
+// splitCode splits Discord message content into text, inline code and code block segments.
+func splitCode(content string) []mdSegment {
+	var segments []mdSegment
+
+	for content != "" {
+		loc := codeBlockRegex.FindStringSubmatchIndex(content)
+		if loc == nil {
+			segments = append(segments, splitInlineCode(content)...)
+			break
+		}
+
+		if loc[0] > 0 {
+			segments = append(segments, splitInlineCode(content[:loc[0]])...)
+		}
+
+		block := mdSegment{kind: segmentCodeBlock, text: content[loc[4]:loc[5]]}
+		if loc[2] >= 0 {
+			block.lang = content[loc[2]:loc[3]]
+		}
+		block.text = strings.TrimPrefix(block.text, "\n")
+		segments = append(segments, block)
+
+		content = content[loc[1]:]
+	}
+
+	return segments
+}
+
+// splitInlineCode splits text into text and inline code segments.
+func splitInlineCode(text string) []mdSegment {
+	var segments []mdSegment
+
+	for text != "" {
+		loc := inlineCodeRegex.FindStringSubmatchIndex(text)
+		if loc == nil {
+			segments = append(segments, mdSegment{kind: segmentText, text: text})
+			break
+		}
+
+		if loc[0] > 0 {
+			segments = append(segments, mdSegment{kind: segmentText, text: text[:loc[0]]})
+		}
+
+		code := ""
+		if loc[2] >= 0 {
+			code = text[loc[2]:loc[3]]
+		} else {
+			code = text[loc[4]:loc[5]]
+		}
+		segments = append(segments, mdSegment{kind: segmentInlineCode, text: code})
+
+		text = text[loc[1]:]
+	}
+
+	return segments
+}
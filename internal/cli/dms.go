@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pterm/pterm"
+
+	"github.com/CaptainFallaway/Discorder/internal/discord"
+)
+
+// DMFilter selects which DM conversations are exported, by recipient ID, username or global name.
+// A conversation is exported if any recipient is included (or Include is empty)
+// and no recipient is excluded.
+type DMFilter struct {
+	Include []string
+	Exclude []string
+}
+
+// matches reports whether a conversation passes the filter
+func (f DMFilter) matches(channel discord.Channel) bool {
+	anyRecipient := func(names []string) bool {
+		return slices.ContainsFunc(channel.Recipients, func(u discord.User) bool {
+			return slices.ContainsFunc(names, func(name string) bool { return matchesUser(u, name) })
+		})
+	}
+	if len(f.Include) > 0 && !anyRecipient(f.Include) {
+		return false
+	}
+	return !anyRecipient(f.Exclude)
+}
+
+// matchesUser reports whether name is the user's ID, username or global name
+func matchesUser(u discord.User, name string) bool {
+	return u.ID == name || strings.EqualFold(u.Username, name) || (u.GlobalName != "" && strings.EqualFold(u.GlobalName, name))
+}
+
+// DMExport is the result of exporting a DM conversation.
+type DMExport struct {
+	Channel  discord.Channel
+	Path     string
+	Messages int
+	Err      error
+}
+
+// ExportDMs exports every DM and group DM matching filter into dir, one file per conversation
+// named after its recipients (or the group's name). Conversations that fail are reported and skipped.
+func ExportDMs(dc *discord.DiscordClient, dir string, filter DMFilter, opts ExportOptions) ([]DMExport, error) {
+	ctx := context.Background()
+
+	channels, err := dc.GetUserChannels(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user channels: %w", err)
+	}
+	SortChannels(channels)
+
+	channels = slices.DeleteFunc(channels, func(c discord.Channel) bool {
+		return (c.Type != discord.ChannelDM && c.Type != discord.ChannelGroupDM) || !filter.matches(c)
+	})
+
+	if opts.Archive != nil {
+		if err := opts.Archive.SaveChannels(ctx, channels...); err != nil {
+			return nil, err
+		}
+	}
+
+	if RendersContent(opts.Format) && opts.Writer.Resolver == nil {
+		opts.Writer.Resolver = NewResolver()
+		for _, c := range channels {
+			opts.Writer.Resolver.AddChannels(c)
+			opts.Writer.Resolver.AddUsers(c.Recipients...)
+		}
+	}
+
+	used := make(map[string]bool)
+	results := make([]DMExport, 0, len(channels))
+	for _, c := range channels {
+		name := getChannelSortName(c)
+		if c.Type == discord.ChannelGroupDM && c.Name == "" {
+			// Unnamed groups are named after all their recipients
+			name = dmTitle(c)
+		}
+		name = uniqueName(used, safeFileName(name), c.ID)
+		path := filepath.Join(dir, name+Extension(opts.Format))
+
+		fmt.Fprintf(os.Stderr, "Exporting %s...\n", path)
+
+		channelOpts := opts
+		channelOpts.Writer.Title = dmTitle(c)
+		written, err := ExportChannel(dc, c.ID, path, channelOpts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to export %s: %v\n", getChannelSortName(c), err)
+		}
+
+		results = append(results, DMExport{Channel: c, Path: path, Messages: written, Err: err})
+	}
+
+	return results, nil
+}
+
+// dmTitle names a DM conversation by its group name or recipients
+func dmTitle(channel discord.Channel) string {
+	if channel.Type == discord.ChannelGroupDM && channel.Name != "" {
+		return channel.Name
+	}
+	names := make([]string, 0, len(channel.Recipients))
+	for _, r := range channel.Recipients {
+		names = append(names, r.GetName())
+	}
+	if len(names) == 0 {
+		return getChannelSortName(channel)
+	}
+	return strings.Join(names, ", ")
+}
+
+// PrintDMExports prints a summary table of exported DM conversations and their message counts.
+func PrintDMExports(results []DMExport) {
+	if len(results) == 0 {
+		fmt.Println("No DM channels matched.")
+		return
+	}
+
+	total := 0
+	table := [][]string{{"Conversation", "Type", "Messages", "File"}}
+	for _, r := range results {
+		file := r.Path
+		if r.Err != nil {
+			file = "failed: " + excerpt(r.Err.Error(), 40)
+		}
+		table = append(table, []string{dmTitle(r.Channel), channelTypeString(r.Channel.Type), fmt.Sprint(r.Messages), file})
+		total += r.Messages
+	}
+
+	fmt.Printf("Exported %d messages from %d conversations:\n\n", total, len(results))
+	pterm.DefaultTable.WithHasHeader().WithData(table).Render()
+}
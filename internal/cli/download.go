@@ -0,0 +1,165 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/CaptainFallaway/Discorder/internal/discord"
+	"github.com/CaptainFallaway/Discorder/internal/download"
+)
+
+// downloadBatch is how many messages are buffered before their files are fetched together
+const downloadBatch = 100
+
+// DownloadOptions selects what a download writer fetches besides attachments.
+type DownloadOptions struct {
+	Embeds   bool
+	Stickers bool
+	// LinkPrefix is prepended to local paths written into messages, typically the
+	// download directory relative to the export file
+	LinkPrefix string
+}
+
+// downloadWriter fetches the files of each batch of messages before passing them on,
+// with their URLs rewritten to the local copies.
+type downloadWriter struct {
+	next    MessageWriter
+	d       *download.Downloader
+	opts    DownloadOptions
+	pending []discord.Message
+}
+
+// NewDownloadWriter returns a MessageWriter that downloads attachments (and optionally embed images
+// and stickers) with d, then writes the messages to next pointing at the downloaded files.
+// Files that fail to download keep their remote URL and a warning is printed.
+func NewDownloadWriter(next MessageWriter, d *download.Downloader, opts DownloadOptions) MessageWriter {
+	return &downloadWriter{next: next, d: d, opts: opts}
+}
+
+func (dw *downloadWriter) WriteMessage(message discord.Message) error {
+	dw.pending = append(dw.pending, message)
+	if len(dw.pending) >= downloadBatch {
+		return dw.drain()
+	}
+	return nil
+}
+
+func (dw *downloadWriter) Flush() error {
+	if err := dw.drain(); err != nil {
+		return err
+	}
+	return flushWriter(dw.next)
+}
+
+func (dw *downloadWriter) Close() error {
+	return errors.Join(dw.drain(), dw.next.Close())
+}
+
+// drain downloads the files of the pending messages and writes them on
+func (dw *downloadWriter) drain() error {
+	if len(dw.pending) == 0 {
+		return nil
+	}
+
+	var jobs []func()
+	for i := range dw.pending {
+		jobs = append(jobs, dw.rewrite(&dw.pending[i])...)
+	}
+
+	concurrency := max(dw.d.Concurrency, 1)
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, job := range jobs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			job()
+		}()
+	}
+	wg.Wait()
+
+	if err := dw.d.SaveIndex(); err != nil {
+		return err
+	}
+
+	for _, message := range dw.pending {
+		if err := dw.next.WriteMessage(message); err != nil {
+			return err
+		}
+	}
+	dw.pending = dw.pending[:0]
+	return nil
+}
+
+// rewrite copies the message's slices so the originals aren't modified, and returns a job
+// per file that downloads it and points the copy at the local path
+func (dw *downloadWriter) rewrite(message *discord.Message) []func() {
+	var jobs []func()
+
+	message.Attachments = append([]discord.Attachment(nil), message.Attachments...)
+	for i := range message.Attachments {
+		a := &message.Attachments[i]
+		remote := a.URL
+		jobs = append(jobs, dw.job("attachment:"+a.ID, remote, a.Size, func(local string) {
+			a.URL, a.ProxyURL = local, local
+		}))
+	}
+
+	if dw.opts.Embeds {
+		message.Embeds = append([]discord.Embed(nil), message.Embeds...)
+		for i := range message.Embeds {
+			e := &message.Embeds[i]
+			for _, media := range []**discord.EmbedMedia{&e.Image, &e.Thumbnail} {
+				if *media == nil || (*media).URL == "" {
+					continue
+				}
+				copied := **media
+				*media = &copied
+				jobs = append(jobs, dw.job(urlKey(copied.URL), copied.URL, 0, func(local string) {
+					copied.URL, copied.ProxyURL = local, local
+				}))
+			}
+		}
+	}
+
+	if dw.opts.Stickers {
+		message.StickerItems = append([]discord.StickerItem(nil), message.StickerItems...)
+		for i := range message.StickerItems {
+			s := &message.StickerItems[i]
+			jobs = append(jobs, dw.job("sticker:"+s.ID, s.URL(), 0, func(local string) {
+				s.LocalPath = local
+			}))
+		}
+	}
+
+	return jobs
+}
+
+// job returns a function downloading a single file and calling set with its local path
+func (dw *downloadWriter) job(key, url string, size int64, set func(local string)) func() {
+	return func() {
+		entry, err := dw.d.Fetch(context.Background(), key, url, size)
+		if err != nil {
+			if !errors.Is(err, download.ErrTooLarge) {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+			return
+		}
+		set(path.Join(dw.opts.LinkPrefix, entry.Path))
+	}
+}
+
+// urlKey identifies a file by its URL without the query, which holds expiring signatures
+func urlKey(url string) string {
+	if i := strings.IndexByte(url, '?'); i >= 0 {
+		url = url[:i]
+	}
+	return "url:" + url
+}
@@ -0,0 +1,159 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/CaptainFallaway/Discorder/internal/archive"
+	"github.com/CaptainFallaway/Discorder/internal/discord"
+)
+
+// ExportOptions configure exporting channels to files, shared by the bulk export commands.
+type ExportOptions struct {
+	Format   string
+	Messages discord.IterMessagesOptions
+	Writer   WriterOptions
+	// Incremental appends only new messages to existing files, tracked in StatePath
+	Incremental bool
+	StatePath   string
+	// Archive, if set, also stores the exported messages
+	Archive *archive.Archive
+	// Pins lists the channel's pinned messages in a section of formats that have sections
+	Pins bool
+	// GuildID is the guild of the exported channels, empty for DMs, for links to pinned messages
+	GuildID string
+}
+
+// ExportChannel exports a channel's messages to a file, returning how many were written.
+// If the first page can't be read the file is removed, so skipped channels leave nothing behind.
+func ExportChannel(dc *discord.DiscordClient, channelID, path string, opts ExportOptions) (int, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return 0, fmt.Errorf("error creating export directory: %w", err)
+	}
+
+	writerOpts := opts.Writer
+	if writerOpts.Downloader != nil {
+		writerOpts.Download.LinkPrefix = DownloadLinkPrefix(path, writerOpts.Downloader.Dir)
+	}
+	if opts.Pins && HasPinsSection(opts.Format) {
+		writerOpts.Pins = exportPins(dc, opts.GuildID, channelID)
+	}
+
+	var extra []MessageWriter
+	if opts.Archive != nil {
+		extra = append(extra, archive.NewWriter(opts.Archive))
+	}
+
+	_, statErr := os.Stat(path)
+	existed := statErr == nil
+
+	var written int
+	var err error
+	if opts.Incremental {
+		written, err = IncrementalExport(dc, channelID, opts.Format, path, opts.StatePath, opts.Messages, writerOpts, extra...)
+	} else {
+		written, err = exportChannelFile(dc, channelID, path, opts.Format, opts.Messages, writerOpts, extra)
+	}
+
+	if err != nil && written == 0 && !existed {
+		os.Remove(path)
+	}
+	return written, err
+}
+
+// exportPins fetches a channel's pins for an export's pinned section. Failing to do so
+// doesn't fail the export, the section is left out instead.
+func exportPins(dc *discord.DiscordClient, guildID, channelID string) []discord.Message {
+	pins, err := ChannelPins(dc, guildID, channelID)
+	if err != nil {
+		if !discord.IsMissingAccess(err) {
+			fmt.Fprintf(os.Stderr, "Warning: failed to get pinned messages: %v\n", err)
+		}
+		return nil
+	}
+	return pins
+}
+
+// exportChannelFile writes a channel's messages to a new file
+func exportChannelFile(dc *discord.DiscordClient, channelID, path, format string, messageOpts discord.IterMessagesOptions, writerOpts WriterOptions, extra []MessageWriter) (int, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, fmt.Errorf("error creating output file: %w", err)
+	}
+	defer f.Close()
+
+	mw, err := NewMessageWriter(format, f, writerOpts)
+	if err != nil {
+		return 0, err
+	}
+	if len(extra) > 0 {
+		mw = MultiWriter(append([]MessageWriter{mw}, extra...)...)
+	}
+
+	cw := &countingWriter{MessageWriter: mw}
+	err = StreamMessages(dc, channelID, messageOpts, cw)
+	return cw.count, errors.Join(err, f.Close())
+}
+
+// countingWriter counts the messages written through it.
+type countingWriter struct {
+	MessageWriter
+	count int
+}
+
+func (cw *countingWriter) WriteMessage(message discord.Message) error {
+	if err := cw.MessageWriter.WriteMessage(message); err != nil {
+		return err
+	}
+	cw.count++
+	return nil
+}
+
+func (cw *countingWriter) Flush() error {
+	return flushWriter(cw.MessageWriter)
+}
+
+// DownloadLinkPrefix returns the download directory as exported messages should link to it:
+// relative to the output file, so the export and its files can be moved together.
+func DownloadLinkPrefix(output, dir string) string {
+	base := "."
+	if output != "" {
+		base = filepath.Dir(output)
+	}
+	if rel, err := filepath.Rel(base, dir); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		return filepath.ToSlash(abs)
+	}
+	return filepath.ToSlash(dir)
+}
+
+// safeFileName makes a channel or user name usable as a file name on any platform
+func safeFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r < 32, strings.ContainsRune(`<>:"/\|?*`, r):
+			return '_'
+		default:
+			return r
+		}
+	}, name)
+	name = strings.Trim(name, " .")
+	if name == "" {
+		return "_"
+	}
+	return name
+}
+
+// uniqueName returns name, or name with the ID appended if it has been used already
+func uniqueName(used map[string]bool, name, id string) string {
+	if used[strings.ToLower(name)] {
+		name = name + "-" + id
+	}
+	used[strings.ToLower(name)] = true
+	return name
+}
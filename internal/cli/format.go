@@ -75,5 +75,13 @@ func FormatTime(sinceStr string) string {
 		return sinceStr // Return original if parsing fails
 	}
 
-	return since.Format("2006-01-02 15:04")
+	return since.UTC().Format("2006-01-02 15:04")
+}
+
+// formatCreated formats a creation date read from an ID in UTC like FormatTime, "-" if the ID had none
+func formatCreated(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format("2006-01-02")
 }
@@ -0,0 +1,187 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/CaptainFallaway/Discorder/internal/discord"
+	"github.com/CaptainFallaway/Discorder/internal/download"
+)
+
+// Export formats of the messages command
+const (
+	FormatJSON  = "json"
+	FormatJSONL = "jsonl"
+)
+
+// Formats lists the supported export formats
+var Formats = []string{FormatJSON, FormatJSONL, FormatHTML, FormatMarkdown, FormatCSV, FormatText}
+
+// ValidateFormat returns an error if format is not a supported export format.
+func ValidateFormat(format string) error {
+	if format == "" || slices.Contains(Formats, format) {
+		return nil
+	}
+	return fmt.Errorf("unknown format %q, available formats: %s", format, strings.Join(Formats, ", "))
+}
+
+// WriterOptions configures a MessageWriter.
+type WriterOptions struct {
+	// Color enables terminal colors where the format supports them
+	Color bool
+	// Appending continues a document previously opened with OpenForAppend
+	Appending bool
+	// Title names the document, e.g. the channel name, where the format has one
+	Title string
+	// Subtitle describes the document below its title, e.g. where a forum post was posted
+	Subtitle string
+	// Tags are shown with the title, e.g. the tags applied to a forum post
+	Tags []string
+	// ExportedAt is when the document was exported, where the format shows it. Zero means now.
+	ExportedAt time.Time
+	// FetchReactors, if set, is used to fetch who reacted to each message
+	FetchReactors *discord.DiscordClient
+	// Pins are listed in a pinned messages section before the messages, where the format has sections
+	Pins []discord.Message
+	// Resolver names the users, channels and roles mentioned in rendered content.
+	// If nil, only the users a message mentions or that wrote earlier messages are resolved.
+	Resolver *Resolver
+	// EmbedAvatars inlines avatar images into self-contained documents instead of linking them
+	EmbedAvatars bool
+	// CSVColumns selects the columns of CSV exports, DefaultCSVColumns if empty
+	CSVColumns []string
+	// CSVAttachmentColumns adds this many attachment URL columns to CSV exports
+	CSVAttachmentColumns int
+	// Downloader, if set, fetches attachments and points the exported messages at the local copies
+	Downloader *download.Downloader
+	// Download selects what else the Downloader fetches and how local paths are linked
+	Download DownloadOptions
+}
+
+// Extension returns the file extension, with the dot, of exports in a format.
+func Extension(format string) string {
+	switch format {
+	case FormatMarkdown:
+		return ".md"
+	case FormatText:
+		return ".txt"
+	case "":
+		return "." + FormatJSON
+	default:
+		return "." + format
+	}
+}
+
+// NewMessageWriter returns a MessageWriter for an export format.
+func NewMessageWriter(format string, w io.Writer, opts WriterOptions) (MessageWriter, error) {
+	var mw MessageWriter
+	switch format {
+	case FormatJSON, "":
+		mw = NewJSONArrayWriter(w, opts.Color, opts.Appending)
+	case FormatJSONL:
+		mw = NewJSONLinesWriter(w)
+	case FormatHTML:
+		mw = NewHTMLWriter(w, opts)
+	case FormatMarkdown:
+		mw = NewMarkdownWriter(w, opts)
+	case FormatCSV:
+		mw = NewCSVWriter(w, opts)
+	case FormatText:
+		mw = NewTextWriter(w, opts)
+	default:
+		return nil, ValidateFormat(format)
+	}
+
+	if opts.Downloader != nil {
+		mw = NewDownloadWriter(mw, opts.Downloader, opts.Download)
+	}
+	if opts.FetchReactors != nil {
+		mw = NewReactorsWriter(mw, opts.FetchReactors)
+	}
+	return mw, nil
+}
+
+// HasPinsSection reports whether exports in a format list pinned messages in a section of their own.
+// Other formats mark pinned messages in place.
+func HasPinsSection(format string) bool {
+	return format == FormatHTML || format == FormatMarkdown || format == FormatText
+}
+
+// RendersContent reports whether exports in a format render message content for reading,
+// resolving mentions, custom emoji and timestamps. Other formats keep the raw content.
+func RendersContent(format string) bool {
+	return format == FormatHTML || format == FormatMarkdown || format == FormatText
+}
+
+// Appendable reports whether exports in a format can be appended to by incremental runs.
+func Appendable(format string) bool {
+	switch format {
+	case FormatJSON, FormatJSONL, FormatText, "":
+		return true
+	default:
+		return false
+	}
+}
+
+// OpenForAppend opens an existing export so more messages can be written to the end of it.
+// It reports whether the export already holds messages.
+func OpenForAppend(format, path string) (*os.File, bool, error) {
+	switch format {
+	case FormatJSON, "":
+		return PrepareJSONArrayAppend(path)
+	case FormatJSONL, FormatText:
+		return prepareLinesAppend(path)
+	default:
+		return nil, false, fmt.Errorf("format %q does not support appending", format)
+	}
+}
+
+// prepareLinesAppend opens a line-based export for appending,
+// dropping a trailing partial line left by an interrupted run.
+func prepareLinesAppend(path string) (*os.File, bool, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, false, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, false, err
+	}
+
+	size := info.Size()
+	tailSize := min(size, 64*1024)
+	tail := make([]byte, tailSize)
+	if _, err := f.ReadAt(tail, size-tailSize); err != nil && err != io.EOF {
+		f.Close()
+		return nil, false, err
+	}
+
+	end := size
+	if len(tail) > 0 && tail[len(tail)-1] != '\n' {
+		idx := bytes.LastIndexByte(tail, '\n')
+		if idx < 0 && tailSize < size {
+			f.Close()
+			return nil, false, fmt.Errorf("cannot find the end of the last complete line in %s", path)
+		}
+		// Keep everything up to and including the last complete line
+		end = size - tailSize + int64(idx+1)
+	}
+
+	if err := f.Truncate(end); err != nil {
+		f.Close()
+		return nil, false, err
+	}
+	if _, err := f.Seek(end, io.SeekStart); err != nil {
+		f.Close()
+		return nil, false, err
+	}
+
+	return f, end > 0, nil
+}
@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/CaptainFallaway/Discorder/internal/discord"
+	"github.com/CaptainFallaway/Discorder/internal/discordtest"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// goldenServer serves a guild channel whose messages exercise the features every format renders
+func goldenServer(t *testing.T) *discordtest.Server {
+	t.Helper()
+	s := discordtest.NewServer()
+	t.Cleanup(s.Close)
+
+	alice := discordtest.NewUser("1", "alice", "Alice")
+	bob := discordtest.NewUser("2", "bob", "")
+	start := time.Date(2025, 1, 2, 12, 0, 0, 0, time.UTC)
+
+	s.Guilds = []discord.Guild{{ID: "10", Name: "Guild"}}
+	s.GuildChannels["10"] = []discord.Channel{{ID: "100", GuildID: "10", Name: "general", Type: discord.ChannelText}}
+	s.GuildRoles["10"] = []discord.Role{{ID: "20", Name: "mods"}}
+
+	hello := discordtest.NewMessage("1001", "100", alice, "Hello **world**, see <#100> and <@2> <@&20>", start)
+	hello.Mentions = []discord.User{bob}
+	hello.Reactions = []discord.Reaction{{Count: 2, CountDetails: discord.ReactionCountDetails{Normal: 2}, Emoji: discord.Emoji{Name: "👍"}}}
+
+	reply := discordtest.NewMessage("1002", "100", bob, "Meeting <t:1735787045:F> (<t:1735787045:R>) ||secret|| <b>not html</b>", start.Add(time.Minute))
+	reply.Type = discord.MessageReply
+	reply.EditedTimestamp = start.Add(2 * time.Minute).Format(time.RFC3339)
+	reply.MessageReference = &discord.MessageReference{MessageID: "1001", ChannelID: "100", GuildID: "10"}
+	reply.ReferencedMessage = &hello
+
+	file := discordtest.NewMessage("1003", "100", alice, "=1+1", start.Add(3*time.Minute))
+	file.Pinned = true
+	file.Attachments = []discord.Attachment{{
+		ID: "500", Filename: "notes.txt", ContentType: "text/plain", Size: 1234,
+		URL: "https://cdn.discordapp.com/attachments/100/500/notes.txt", ProxyURL: "https://media.discordapp.net/attachments/100/500/notes.txt",
+	}}
+
+	code := discordtest.NewMessage("1004", "100", bob, "```go\nfmt.Println(\"<hi>\")\n```", start.Add(time.Hour))
+	code.Embeds = []discord.Embed{{Type: "rich", Title: "Release", Description: "Version *2* is out", URL: "https://example.com/release", Color: 0x5865f2}}
+
+	s.AddMessages("100", hello, reply, file, code)
+	return s
+}
+
+func TestExportGolden(t *testing.T) {
+	s := goldenServer(t)
+	dc := s.Client(discord.WithRetryPolicy(discord.NoRetries))
+	channel, err := dc.GetChannel(t.Context(), "100")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range Formats {
+		t.Run(format, func(t *testing.T) {
+			t.Parallel()
+			opts := ExportOptions{Format: format, Messages: discord.IterMessagesOptions{OldestFirst: true}, Pins: true, GuildID: channel.GuildID}
+			opts.Writer.Title = ChannelTitle(channel)
+			opts.Writer.ExportedAt = time.Date(2025, 2, 1, 9, 30, 0, 0, time.UTC)
+			if RendersContent(format) {
+				opts.Writer.Resolver = LoadResolver(dc, channel)
+			}
+
+			path := filepath.Join(t.TempDir(), "general"+Extension(format))
+			if _, err := ExportChannel(dc, channel.ID, path, opts); err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			golden := filepath.Join("testdata", "golden", "general"+Extension(format))
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("export differs from %s, rerun with -update if the change is intended:\n%s", golden, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/CaptainFallaway/Discorder/internal/discord"
+)
+
+// ExportForum exports every post of a forum or media channel, active and archived, into dir
+// as one document per post, and writes a manifest listing the posts with their tags.
+// Each document holds the post's starter message followed by its replies.
+func ExportForum(dc *discord.DiscordClient, forum discord.Channel, dir string, opts ExportOptions) (*GuildManifest, error) {
+	ctx := context.Background()
+
+	if !forum.IsForum() {
+		return nil, fmt.Errorf("channel %s is not a forum or media channel", forum.ID)
+	}
+
+	posts, err := dc.GetChannelThreads(ctx, forum.GuildID, forum.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list forum posts: %w", err)
+	}
+
+	if opts.Archive != nil {
+		if err := opts.Archive.SaveChannels(ctx, append([]discord.Channel{forum}, posts...)...); err != nil {
+			return nil, err
+		}
+	}
+
+	opts.GuildID = forum.GuildID
+	if RendersContent(opts.Format) && opts.Writer.Resolver == nil {
+		opts.Writer.Resolver = LoadResolver(dc, forum)
+		opts.Writer.Resolver.AddChannels(posts...)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating export directory: %w", err)
+	}
+
+	manifest := &GuildManifest{
+		Guild:      discord.Guild{ID: forum.GuildID},
+		Format:     cmp.Or(opts.Format, FormatJSON),
+		ExportedAt: time.Now().UTC(),
+	}
+	manifestPath := filepath.Join(dir, ManifestFile)
+
+	// Posts are written directly into dir, rather than a directory named after the forum
+	used := make(map[string]bool)
+	entry := ManifestChannel{ID: forum.ID, Name: forum.Name, Type: channelTypeString(forum.Type)}
+	manifest.Channels = append(manifest.Channels, forumManifestChannel(entry, forum, len(posts)))
+
+	for _, post := range posts {
+		postOpts := opts
+		postOpts.Writer = forumPostOptions(opts.Writer, forum, post)
+
+		postEntry := ManifestChannel{
+			ID:       post.ID,
+			Name:     post.Name,
+			Type:     channelTypeString(post.Type),
+			ParentID: post.ParentID,
+			Path:     uniqueName(used, safeFileName(post.Name), post.ID) + Extension(opts.Format),
+			Tags:     postOpts.Writer.Tags,
+		}
+		manifest.Channels = append(manifest.Channels, exportManifestChannel(dc, dir, postEntry, postOpts))
+
+		if err := manifest.Save(manifestPath); err != nil {
+			return manifest, err
+		}
+	}
+
+	return manifest, manifest.Save(manifestPath)
+}
+
+// forumManifestChannel completes the manifest entry of a forum, whose posts are exported
+// into the directory named after it
+func forumManifestChannel(entry ManifestChannel, forum discord.Channel, posts int) ManifestChannel {
+	if entry.Path != "" {
+		entry.Path = strings.TrimSuffix(entry.Path, filepath.Ext(entry.Path)) + "/"
+	}
+	entry.Posts = posts
+	entry.Status = ExportStatusExported
+	for _, tag := range forum.AvailableTags {
+		entry.Tags = append(entry.Tags, forumTagName(tag))
+	}
+	return entry
+}
+
+// forumPostOptions returns the writer options of a forum post's document:
+// titled after the post, with its tags and when and where it was posted
+func forumPostOptions(opts WriterOptions, forum, post discord.Channel) WriterOptions {
+	opts.Title = post.Name
+	opts.Tags = nil
+	for _, id := range post.AppliedTags {
+		i := slices.IndexFunc(forum.AvailableTags, func(t discord.ForumTag) bool { return t.ID == id })
+		if i >= 0 {
+			opts.Tags = append(opts.Tags, forumTagName(forum.AvailableTags[i]))
+		}
+	}
+
+	opts.Subtitle = "Posted in #" + forum.Name
+	if created := post.CreatedAt(); !created.IsZero() {
+		opts.Subtitle += " on " + created.Format("2 January 2006")
+	}
+	return opts
+}
+
+// forumTagName returns a tag's name, prefixed with its emoji if it is a unicode emoji
+func forumTagName(tag discord.ForumTag) string {
+	if tag.EmojiName != "" && tag.EmojiID == "" {
+		return tag.EmojiName + " " + tag.Name
+	}
+	return tag.Name
+}
@@ -0,0 +1,358 @@
+package cli
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pterm/pterm"
+
+	"github.com/CaptainFallaway/Discorder/internal/discord"
+)
+
+// ManifestFile is the name of the manifest written into a guild export directory
+const ManifestFile = "manifest.json"
+
+// Statuses of channels in a guild manifest
+const (
+	ExportStatusExported = "exported"
+	ExportStatusSkipped  = "skipped"
+	ExportStatusFailed   = "failed"
+)
+
+// GuildManifest describes a guild export: every channel considered and where it was written.
+type GuildManifest struct {
+	Guild      discord.Guild     `json:"guild"`
+	Format     string            `json:"format"`
+	ExportedAt time.Time         `json:"exported_at"`
+	Channels   []ManifestChannel `json:"channels"`
+}
+
+// ManifestChannel is a channel of a guild export.
+type ManifestChannel struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Category string `json:"category,omitempty"`
+	ParentID string `json:"parent_id,omitempty"`
+	// Path is relative to the export directory
+	Path     string `json:"path,omitempty"`
+	Messages int    `json:"messages"`
+	// Posts counts the posts of a forum, each exported like a thread
+	Posts int `json:"posts,omitempty"`
+	// Tags are the available tags of a forum, or the tags applied to a forum post
+	Tags   []string `json:"tags,omitempty"`
+	Status string   `json:"status"`
+	Error  string   `json:"error,omitempty"`
+}
+
+// exportableChannel reports whether a channel type holds messages that can be exported
+func exportableChannel(t int) bool {
+	switch t {
+	case discord.ChannelText, discord.ChannelGuildAnnouncement, discord.ChannelGuildForum, discord.ChannelGuildMedia,
+		discord.ChannelAnnouncementThread, discord.ChannelPublicThread, discord.ChannelPrivateThread:
+		return true
+	default:
+		return false
+	}
+}
+
+// hasThreads reports whether a channel type can have threads
+func hasThreads(t int) bool {
+	switch t {
+	case discord.ChannelText, discord.ChannelGuildAnnouncement, discord.ChannelGuildForum, discord.ChannelGuildMedia:
+		return true
+	default:
+		return false
+	}
+}
+
+// ExportGuild exports every readable channel of a guild into dir, one directory per category,
+// and writes a manifest of the export. Threads, including archived ones, are exported into
+// a directory named after their parent channel. Channels the user can't read are skipped.
+func ExportGuild(dc *discord.DiscordClient, guildID, dir string, opts ExportOptions) (*GuildManifest, error) {
+	ctx := context.Background()
+
+	channels, err := dc.GetGuildChannels(ctx, guildID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get guild channels: %w", err)
+	}
+
+	guild := discord.Guild{ID: guildID}
+	guilds, err := dc.GetUserGuilds(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get guilds: %w", err)
+	}
+	for _, g := range guilds {
+		if g.ID == guildID {
+			guild = g
+		}
+	}
+
+	// The channel list doesn't include threads. Active threads are listed for the whole guild,
+	// archived threads per channel.
+	active, err := dc.GetGuildActiveThreads(ctx, guildID)
+	if err != nil && !discord.IsMissingAccess(err) {
+		fmt.Fprintf(os.Stderr, "Warning: failed to list active threads: %v\n", err)
+	}
+	threads := make(map[string][]discord.Channel)
+	all := slices.Clone(channels)
+	for _, c := range sortGuildChannels(channels) {
+		if !hasThreads(c.Type) {
+			continue
+		}
+		archived, err := dc.GetArchivedThreads(ctx, c.ID)
+		if err != nil && !discord.IsMissingAccess(err) {
+			fmt.Fprintf(os.Stderr, "Warning: failed to list archived threads of #%s: %v\n", c.Name, err)
+		}
+		list := discord.MergeThreads(discord.ThreadsOf(active, c.ID), archived)
+		if len(list) == 0 {
+			continue
+		}
+		threads[c.ID] = list
+		all = append(all, list...)
+	}
+
+	opts.GuildID = guildID
+	// One resolver serves every channel, so mentions of channels and threads elsewhere in the guild resolve
+	if RendersContent(opts.Format) && opts.Writer.Resolver == nil {
+		opts.Writer.Resolver = NewResolver()
+		opts.Writer.Resolver.AddChannels(all...)
+		opts.Writer.Resolver.loadRoles(ctx, dc, guildID)
+	}
+
+	if opts.Archive != nil {
+		if err := opts.Archive.SaveGuilds(ctx, guild); err != nil {
+			return nil, err
+		}
+		if err := opts.Archive.SaveChannels(ctx, all...); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating export directory: %w", err)
+	}
+
+	manifest := &GuildManifest{
+		Guild:      guild,
+		Format:     cmp.Or(opts.Format, FormatJSON),
+		ExportedAt: time.Now().UTC(),
+	}
+
+	paths := guildChannelPaths(all, opts.Format)
+	manifestPath := filepath.Join(dir, ManifestFile)
+	add := func(entry ManifestChannel) error {
+		manifest.Channels = append(manifest.Channels, entry)
+		// Keep the manifest current, so an interrupted export still describes what was written
+		return manifest.Save(manifestPath)
+	}
+
+	for _, c := range sortGuildChannels(channels) {
+		if !exportableChannel(c.Type) {
+			continue
+		}
+
+		entry := newManifestChannel(c, all, paths)
+		if c.IsForum() {
+			// Forums have no messages of their own, their posts are threads
+			entry = forumManifestChannel(entry, c, len(threads[c.ID]))
+		} else {
+			channelOpts := opts
+			channelOpts.Writer.Title = "#" + c.Name
+			entry = exportManifestChannel(dc, dir, entry, channelOpts)
+		}
+		if err := add(entry); err != nil {
+			return manifest, err
+		}
+
+		for _, t := range threads[c.ID] {
+			threadOpts := opts
+			threadOpts.Writer.Title = "#" + t.Name
+			threadEntry := newManifestChannel(t, all, paths)
+			if c.IsForum() {
+				threadOpts.Writer = forumPostOptions(opts.Writer, c, t)
+				threadEntry.Tags = threadOpts.Writer.Tags
+			}
+			if err := add(exportManifestChannel(dc, dir, threadEntry, threadOpts)); err != nil {
+				return manifest, err
+			}
+		}
+	}
+
+	return manifest, manifest.Save(manifestPath)
+}
+
+// newManifestChannel describes a channel to be exported to its path in paths
+func newManifestChannel(c discord.Channel, channels []discord.Channel, paths map[string]string) ManifestChannel {
+	return ManifestChannel{
+		ID:       c.ID,
+		Name:     c.Name,
+		Type:     channelTypeString(c.Type),
+		Category: categoryName(channels, c),
+		ParentID: c.ParentID,
+		Path:     paths[c.ID],
+	}
+}
+
+// exportManifestChannel exports a channel to its manifest path under dir and records the outcome.
+// Channels the user can't read are marked skipped.
+func exportManifestChannel(dc *discord.DiscordClient, dir string, entry ManifestChannel, opts ExportOptions) ManifestChannel {
+	fmt.Fprintf(os.Stderr, "Exporting %s...\n", entry.Path)
+
+	written, err := ExportChannel(dc, entry.ID, filepath.Join(dir, filepath.FromSlash(entry.Path)), opts)
+	entry.Messages = written
+	entry.Status = ExportStatusExported
+
+	switch {
+	case err != nil && discord.IsMissingAccess(err) && written == 0:
+		entry.Path = ""
+		entry.Status = ExportStatusSkipped
+		entry.Error = "missing access"
+	case err != nil:
+		entry.Status = ExportStatusFailed
+		entry.Error = err.Error()
+		fmt.Fprintf(os.Stderr, "Warning: failed to export #%s: %v\n", entry.Name, err)
+	}
+	return entry
+}
+
+// Save writes the manifest as indented JSON.
+func (m *GuildManifest) Save(path string) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		return fmt.Errorf("error writing manifest: %w", err)
+	}
+	return nil
+}
+
+// PrintManifest prints a table of the channels in a guild or forum export, headed by what was exported.
+func PrintManifest(what string, m *GuildManifest) {
+	table := [][]string{{"Channel", "Category", "Messages", "Status", "Path"}}
+	for _, c := range m.Channels {
+		status := c.Status
+		if c.Error != "" {
+			status += ": " + excerpt(c.Error, 40)
+		}
+		messages := fmt.Sprint(c.Messages)
+		if c.Posts > 0 {
+			messages = fmt.Sprintf("%d posts", c.Posts)
+		}
+		table = append(table, []string{"#" + c.Name, c.Category, messages, status, c.Path})
+	}
+
+	fmt.Printf("Exported %s:\n\n", what)
+	pterm.DefaultTable.WithHasHeader().WithData(table).Render()
+}
+
+// guildChannelPaths lays channels out as <category>/<channel><ext>, with threads in a directory
+// named after their parent channel. Paths are relative and use forward slashes.
+func guildChannelPaths(channels []discord.Channel, format string) map[string]string {
+	// Names must be unique within their directory
+	dirs := make(map[string]string)
+	used := make(map[string]map[string]bool)
+	name := func(parent string, c discord.Channel) string {
+		if used[parent] == nil {
+			used[parent] = make(map[string]bool)
+		}
+		return uniqueName(used[parent], safeFileName(c.Name), c.ID)
+	}
+
+	sorted := sortGuildChannels(channels)
+	for _, c := range sorted {
+		if c.Type == discord.ChannelGuildCategory {
+			dirs[c.ID] = name("", c)
+		}
+	}
+
+	paths := make(map[string]string)
+	for _, c := range sorted {
+		if c.Type == discord.ChannelGuildCategory || c.IsThread() {
+			continue
+		}
+		parent := dirs[c.ParentID]
+		base := name(parent, c)
+		dirs[c.ID] = strings.TrimPrefix(parent+"/"+base, "/")
+		paths[c.ID] = dirs[c.ID] + Extension(format)
+	}
+
+	for _, c := range sorted {
+		if !c.IsThread() {
+			continue
+		}
+		parent, ok := dirs[c.ParentID]
+		if !ok {
+			parent = "threads"
+		}
+		paths[c.ID] = parent + "/" + name(parent, c) + Extension(format)
+	}
+
+	return paths
+}
+
+// categoryName returns the name of a channel's category, following threads to their parent
+func categoryName(channels []discord.Channel, c discord.Channel) string {
+	for range 2 {
+		if c.ParentID == "" {
+			return ""
+		}
+		i := slices.IndexFunc(channels, func(p discord.Channel) bool { return p.ID == c.ParentID })
+		if i < 0 {
+			return ""
+		}
+		if channels[i].Type == discord.ChannelGuildCategory {
+			return channels[i].Name
+		}
+		c = channels[i]
+	}
+	return ""
+}
+
+// sortGuildChannels returns channels in the order Discord shows them: uncategorized channels
+// first, then each category followed by its channels, by position and then ID
+func sortGuildChannels(channels []discord.Channel) []discord.Channel {
+	positions := make(map[string]int, len(channels))
+	for _, c := range channels {
+		if c.Type == discord.ChannelGuildCategory {
+			positions[c.ID] = c.Position
+		}
+	}
+	group := func(c discord.Channel) int {
+		if c.Type == discord.ChannelGuildCategory {
+			return c.Position
+		}
+		if pos, ok := positions[c.ParentID]; ok {
+			return pos
+		}
+		return -1
+	}
+
+	sorted := slices.Clone(channels)
+	slices.SortStableFunc(sorted, func(a, b discord.Channel) int {
+		return cmp.Or(
+			cmp.Compare(group(a), group(b)),
+			// A category comes before its channels
+			cmp.Compare(boolInt(a.Type != discord.ChannelGuildCategory), boolInt(b.Type != discord.ChannelGuildCategory)),
+			cmp.Compare(boolInt(a.IsThread()), boolInt(b.IsThread())),
+			cmp.Compare(a.Position, b.Position),
+			discord.CompareIDs(a.ID, b.ID),
+		)
+	})
+	return sorted
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
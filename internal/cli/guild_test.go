@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CaptainFallaway/Discorder/internal/discord"
+	"github.com/CaptainFallaway/Discorder/internal/discordtest"
+)
+
+func TestExportGuildThreads(t *testing.T) {
+	s := discordtest.NewServer()
+	defer s.Close()
+
+	alice := discordtest.NewUser("1", "alice", "Alice")
+	start := time.Date(2025, 1, 2, 12, 0, 0, 0, time.UTC)
+
+	s.Guilds = []discord.Guild{{ID: "10", Name: "Guild"}}
+	s.GuildChannels["10"] = []discord.Channel{
+		{ID: "100", GuildID: "10", Name: "general", Type: discord.ChannelText},
+		{ID: "200", GuildID: "10", Name: "random", Type: discord.ChannelText},
+		{ID: "300", GuildID: "10", Name: "secret", Type: discord.ChannelText},
+	}
+	s.Threads["100"] = []discord.Channel{
+		discordtest.NewThread("110", "100", "active thread", time.Time{}),
+		discordtest.NewThread("120", "100", "old thread", start),
+	}
+	s.Threads["200"] = []discord.Channel{discordtest.NewThread("210", "200", "other thread", time.Time{})}
+	for _, id := range []string{"100", "200", "110", "120", "210"} {
+		s.AddMessages(id, discordtest.NewMessage(id+"1", id, alice, "hello in "+id, start))
+	}
+	s.FailPath("/channels/300/messages", 403, 50001, "Missing Access")
+
+	dir := t.TempDir()
+	manifest, err := ExportGuild(s.Client(discord.WithRetryPolicy(discord.NoRetries)), "10", dir, ExportOptions{Format: FormatText})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status := make(map[string]ManifestChannel)
+	for _, c := range manifest.Channels {
+		status[c.ID] = c
+	}
+	for _, id := range []string{"100", "200", "110", "120", "210"} {
+		c, ok := status[id]
+		if !ok || c.Status != ExportStatusExported || c.Messages != 1 {
+			t.Errorf("channel %s: %+v, want exported with 1 message", id, c)
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(c.Path))); err != nil {
+			t.Errorf("channel %s: %v", id, err)
+		}
+	}
+	if c := status["300"]; c.Status != ExportStatusSkipped {
+		t.Errorf("channel 300 status = %q, want skipped", c.Status)
+	}
+	if c := status["120"]; c.ParentID != "100" || filepath.Dir(c.Path) != "general" {
+		t.Errorf("thread 120 = %+v, want it under general", c)
+	}
+
+	active := 0
+	for _, r := range s.Requests {
+		if r.Path == "/guilds/10/threads/active" {
+			active++
+		}
+	}
+	if active != 1 {
+		t.Errorf("listed active threads %d times, want once per guild", active)
+	}
+}
+
+func TestExportGuildPins(t *testing.T) {
+	s := discordtest.NewServer()
+	defer s.Close()
+
+	alice := discordtest.NewUser("1", "alice", "Alice")
+	start := time.Date(2025, 1, 2, 12, 0, 0, 0, time.UTC)
+
+	s.Guilds = []discord.Guild{{ID: "10", Name: "Guild"}}
+	s.GuildChannels["10"] = []discord.Channel{{ID: "100", GuildID: "10", Name: "general", Type: discord.ChannelText}}
+	s.GuildRoles["10"] = nil
+	pinned := discordtest.NewMessage("1001", "100", alice, "read the rules", start)
+	pinned.Pinned = true
+	s.AddMessages("100", pinned, discordtest.NewMessage("1002", "100", alice, "hello", start.Add(time.Minute)))
+
+	dir := t.TempDir()
+	opts := ExportOptions{Format: FormatMarkdown, Pins: true}
+	if _, err := ExportGuild(s.Client(discord.WithRetryPolicy(discord.NoRetries)), "10", dir, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := os.ReadFile(filepath.Join(dir, "general.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link := "https://discord.com/channels/10/100/1001"; !strings.Contains(string(out), link) {
+		t.Errorf("export has no jump link %s to the pinned message:\n%s", link, out)
+	}
+	for _, r := range s.Requests {
+		if r.Path == "/channels/100" {
+			t.Errorf("fetched channel 100 again for the guild of its pins")
+		}
+	}
+}
@@ -52,7 +52,7 @@ func printGroupDMs(channels []discord.Channel) error {
 	}
 
 	// Create table data for Group DMs
-	tableData := [][]string{{"Channel ID", "Name", "Recipients"}}
+	tableData := [][]string{{"Channel ID", "Name", "Recipients", "Created"}}
 
 	for _, channel := range channels {
 		// Build recipients list
@@ -70,7 +70,7 @@ func printGroupDMs(channels []discord.Channel) error {
 			channelName = "Unnamed Group"
 		}
 
-		tableData = append(tableData, []string{channel.ID, channelName, recipients})
+		tableData = append(tableData, []string{channel.ID, channelName, recipients, formatCreated(channel.CreatedAt())})
 	}
 
 	fmt.Printf("Found %d group DM channels:\n\n", len(channels))
@@ -87,16 +87,18 @@ func printPrivateDMs(channels []discord.Channel) error {
 	}
 
 	// Create table data for Private DMs (no recipients column)
-	tableData := [][]string{{"Channel ID", "User"}}
+	tableData := [][]string{{"Channel ID", "User", "Account Created"}}
 
 	for _, channel := range channels {
 		// For private DMs, show the other user's name
 		userName := "Unknown User"
+		accountCreated := "-"
 		if len(channel.Recipients) > 0 {
 			userName = channel.Recipients[0].GetName()
+			accountCreated = formatCreated(channel.Recipients[0].CreatedAt())
 		}
 
-		tableData = append(tableData, []string{channel.ID, userName})
+		tableData = append(tableData, []string{channel.ID, userName, accountCreated})
 	}
 
 	fmt.Printf("Found %d private DM channels:\n\n", len(channels))
@@ -118,7 +120,7 @@ func PrintRelationships(dc *discord.DiscordClient) error {
 	SortRelationships(relationships)
 
 	// Create table data
-	tableData := [][]string{{"User ID", "Global Name (Username) aka [Nickname]", "Type", "Since"}}
+	tableData := [][]string{{"User ID", "Global Name (Username) aka [Nickname]", "Type", "Since", "Account Created"}}
 
 	for _, rel := range relationships {
 		relationshipType := relationshipTypeString(rel.Type)
@@ -131,7 +133,7 @@ func PrintRelationships(dc *discord.DiscordClient) error {
 			name = fmt.Sprintf("%s aka [%s]", name, rel.Nickname)
 		}
 
-		tableData = append(tableData, []string{rel.User.ID, name, relationshipType, since})
+		tableData = append(tableData, []string{rel.User.ID, name, relationshipType, since, formatCreated(rel.User.CreatedAt())})
 	}
 
 	fmt.Printf("Found %d relationships:\n\n", len(relationships))
@@ -173,7 +175,7 @@ func PrintGuilds(dc *discord.DiscordClient) error {
 		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
 	})
 
-	table := [][]string{{"Guild ID", "Name", "Owner Of", "NSFW Level", "Description"}}
+	table := [][]string{{"Guild ID", "Name", "Owner Of", "NSFW Level", "Created", "Description"}}
 	for _, g := range guilds {
 		owner := "No"
 		if g.Owner {
@@ -184,7 +186,7 @@ func PrintGuilds(dc *discord.DiscordClient) error {
 		if desc == "" {
 			desc = "-"
 		}
-		table = append(table, []string{g.ID, g.Name, owner, nsfw, desc})
+		table = append(table, []string{g.ID, g.Name, owner, nsfw, formatCreated(g.CreatedAt()), desc})
 	}
 
 	fmt.Printf("Found %d guilds:\n\n", len(guilds))
@@ -230,13 +232,13 @@ func PrintGuildChannels(dc *discord.DiscordClient, guildID string) error {
 		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
 	})
 
-	table := [][]string{{"Channel ID", "Type", "Name", "NSFW"}}
+	table := [][]string{{"Channel ID", "Type", "Name", "NSFW", "Created"}}
 	for _, c := range chns {
 		nsfw := "No"
 		if c.NSFW {
 			nsfw = "Yes"
 		}
-		table = append(table, []string{c.ID, channelTypeString(c.Type), c.Name, nsfw})
+		table = append(table, []string{c.ID, channelTypeString(c.Type), c.Name, nsfw, formatCreated(c.CreatedAt())})
 	}
 
 	fmt.Printf("Found %d channels:\n\n", len(chns))
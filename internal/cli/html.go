@@ -0,0 +1,516 @@
+package cli
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/CaptainFallaway/Discorder/internal/discord"
+)
+
+// FormatHTML renders a self-contained HTML transcript
+const FormatHTML = "html"
+
+// htmlWriter renders messages as an offline HTML transcript resembling the Discord client.
+// Consecutive messages by the same author are grouped under one header.
+type htmlWriter struct {
+	w      io.Writer
+	opts   WriterOptions
+	client *http.Client
+
+	started   bool
+	groupOpen bool
+	prev      *discord.Message
+	avatars   map[string]string // avatar URL -> data URI
+	resolver  *Resolver
+	err       error
+}
+
+// NewHTMLWriter returns a MessageWriter producing a single HTML file.
+// Messages must be written oldest first.
+func NewHTMLWriter(w io.Writer, opts WriterOptions) MessageWriter {
+	resolver := opts.Resolver
+	if resolver == nil {
+		resolver = NewResolver()
+	}
+	return &htmlWriter{
+		w:        w,
+		opts:     opts,
+		client:   &http.Client{Timeout: 15 * time.Second},
+		avatars:  make(map[string]string),
+		resolver: resolver,
+	}
+}
+
+func (hw *htmlWriter) WriteMessage(message discord.Message) error {
+	if err := hw.start(); err != nil {
+		return err
+	}
+	hw.resolver.Observe(message)
+
+	if isSystemMessage(message) {
+		if err := hw.closeGroup(); err != nil {
+			return err
+		}
+		hw.prev = nil
+		return htmlTemplates.ExecuteTemplate(hw.w, "system", hw.messageView(message))
+	}
+
+	if !hw.groupOpen || !sameGroup(hw.prev, message) {
+		if err := hw.closeGroup(); err != nil {
+			return err
+		}
+		if err := htmlTemplates.ExecuteTemplate(hw.w, "group", hw.groupView(message)); err != nil {
+			return err
+		}
+		hw.groupOpen = true
+	}
+
+	hw.prev = &message
+	return htmlTemplates.ExecuteTemplate(hw.w, "message", hw.messageView(message))
+}
+
+func (hw *htmlWriter) Close() error {
+	if err := hw.start(); err != nil {
+		return err
+	}
+	if err := hw.closeGroup(); err != nil {
+		return err
+	}
+	return htmlTemplates.ExecuteTemplate(hw.w, "footer", nil)
+}
+
+// start writes the document header once
+func (hw *htmlWriter) start() error {
+	if hw.started {
+		return nil
+	}
+	hw.started = true
+
+	title := hw.opts.Title
+	if title == "" {
+		title = "Discord transcript"
+	}
+	exported := hw.opts.ExportedAt
+	if exported.IsZero() {
+		exported = time.Now()
+	}
+	err := htmlTemplates.ExecuteTemplate(hw.w, "header", map[string]any{
+		"Title":     title,
+		"Subtitle":  hw.opts.Subtitle,
+		"Tags":      hw.opts.Tags,
+		"Generated": exported.UTC().Format("2006-01-02 15:04"),
+	})
+	if err != nil || len(hw.opts.Pins) == 0 {
+		return err
+	}
+
+	pins := make([]htmlPinView, 0, len(hw.opts.Pins))
+	for _, m := range hw.opts.Pins {
+		pins = append(pins, htmlPinView{
+			ID:      m.ID,
+			Author:  displayName(m.Author),
+			Stamp:   FormatTime(m.Timestamp),
+			Excerpt: hw.resolver.resolveText(pinExcerpt(m, 120), m.Mentions),
+			JumpURL: m.JumpURL(),
+		})
+	}
+	return htmlTemplates.ExecuteTemplate(hw.w, "pins", pins)
+}
+
+// htmlPinView is the template data of a pinned message
+type htmlPinView struct {
+	ID      string
+	Author  string
+	Stamp   string
+	Excerpt string
+	JumpURL string
+}
+
+func (hw *htmlWriter) closeGroup() error {
+	if !hw.groupOpen {
+		return nil
+	}
+	hw.groupOpen = false
+	_, err := io.WriteString(hw.w, "</div></div>\n")
+	return err
+}
+
+// avatar returns the avatar image source for a user, inlined as a data URI if requested.
+// It is either a CDN URL we build ourselves or a data URI, so it is trusted as a URL.
+// Every other URL comes from message content and is left to html/template to sanitize.
+func (hw *htmlWriter) avatar(u discord.User) template.URL {
+	url := u.AvatarURL()
+	if !hw.opts.EmbedAvatars {
+		return template.URL(url)
+	}
+	if data, ok := hw.avatars[url]; ok {
+		return template.URL(data)
+	}
+
+	data := url
+	if resp, err := hw.client.Get(url); err == nil {
+		b, readErr := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		resp.Body.Close()
+		if readErr == nil && resp.StatusCode == http.StatusOK {
+			contentType := resp.Header.Get("Content-Type")
+			if contentType == "" {
+				contentType = http.DetectContentType(b)
+			}
+			data = fmt.Sprintf("data:%s;base64,%s", contentType, base64.StdEncoding.EncodeToString(b))
+		}
+	}
+	hw.avatars[url] = data
+	return template.URL(data)
+}
+
+// htmlGroupView is the template data of an author header
+type htmlGroupView struct {
+	Avatar    template.URL
+	Author    string
+	Bot       bool
+	Timestamp string
+}
+
+func (hw *htmlWriter) groupView(message discord.Message) htmlGroupView {
+	return htmlGroupView{
+		Avatar:    hw.avatar(message.Author),
+		Author:    displayName(message.Author),
+		Timestamp: FormatTime(message.Timestamp),
+	}
+}
+
+// htmlMessageView is the template data of a single message
+type htmlMessageView struct {
+	ID          string
+	Time        string
+	Timestamp   string
+	Edited      string
+	Content     template.HTML
+	System      string
+	Reply       *htmlReplyView
+	Attachments []htmlAttachmentView
+	Embeds      []htmlEmbedView
+	Reactions   []htmlReactionView
+}
+
+type htmlReplyView struct {
+	Author  string
+	Avatar  template.URL
+	Content template.HTML
+	Missing bool
+}
+
+type htmlAttachmentView struct {
+	URL      string
+	Filename string
+	Size     string
+	Image    bool
+	Video    bool
+}
+
+type htmlEmbedView struct {
+	Color       string
+	Author      string
+	AuthorURL   string
+	Title       string
+	URL         string
+	Description template.HTML
+	Fields      []discord.EmbedField
+	Image       string
+	Thumbnail   string
+	Footer      string
+}
+
+type htmlReactionView struct {
+	Emoji    string
+	EmojiURL string
+	Count    int
+	Burst    bool
+	Users    string
+}
+
+func (hw *htmlWriter) messageView(message discord.Message) htmlMessageView {
+	view := htmlMessageView{
+		ID:        message.ID,
+		Time:      formatClock(message.Timestamp),
+		Timestamp: FormatTime(message.Timestamp),
+		Content:   renderDiscordHTML(message.Content, message.Mentions, hw.resolver),
+	}
+
+	if message.IsEdited() {
+		view.Edited = FormatTime(message.EditedTimestamp)
+	}
+
+	if isSystemMessage(message) {
+		view.System = systemMessageText(message, displayName)
+	}
+
+	if ref := message.ReferencedMessage; ref != nil {
+		view.Reply = &htmlReplyView{
+			Author:  displayName(ref.Author),
+			Avatar:  hw.avatar(ref.Author),
+			Content: renderDiscordHTML(excerpt(strings.Join(strings.Fields(ref.Content), " "), 200), ref.Mentions, hw.resolver),
+		}
+	} else if message.MessageReference != nil && message.Type == discord.MessageReply {
+		view.Reply = &htmlReplyView{Missing: true}
+	}
+
+	for _, a := range message.Attachments {
+		view.Attachments = append(view.Attachments, htmlAttachmentView{
+			URL:      a.URL,
+			Filename: a.Filename,
+			Size:     formatBytes(a.Size),
+			Image:    strings.HasPrefix(a.ContentType, "image/"),
+			Video:    strings.HasPrefix(a.ContentType, "video/"),
+		})
+	}
+
+	for _, e := range message.Embeds {
+		ev := htmlEmbedView{
+			Title:       e.Title,
+			URL:         e.URL,
+			Description: renderDiscordHTML(e.Description, nil, hw.resolver),
+			Fields:      e.Fields,
+		}
+		if e.Color != 0 {
+			ev.Color = fmt.Sprintf("#%06x", e.Color)
+		}
+		if e.Author != nil {
+			ev.Author = e.Author.Name
+			ev.AuthorURL = e.Author.URL
+		}
+		if e.Image != nil {
+			ev.Image = e.Image.URL
+		}
+		if e.Thumbnail != nil {
+			ev.Thumbnail = e.Thumbnail.URL
+		}
+		if e.Footer != nil {
+			ev.Footer = e.Footer.Text
+		}
+		view.Embeds = append(view.Embeds, ev)
+	}
+
+	for _, r := range message.Reactions {
+		view.Reactions = append(view.Reactions, htmlReactionView{
+			Emoji:    r.Emoji.Name,
+			EmojiURL: r.Emoji.URL(),
+			Count:    r.Count,
+			Burst:    r.CountDetails.Burst > 0,
+			Users:    strings.Join(reactorNames(r), ", "),
+		})
+	}
+
+	return view
+}
+
+var (
+	htmlURLRegex       = regexp.MustCompile(`https?://[^\s<>"\x01]+[^\s<>".,:;!?)\]'*_~|\x01]`)
+	htmlSpoilerRegex   = regexp.MustCompile(`\|\|(.+?)\|\|`)
+	htmlBoldRegex      = regexp.MustCompile(`\*\*(.+?)\*\*`)
+	htmlUnderlineRegex = regexp.MustCompile(`__(.+?)__`)
+	htmlItalicRegex    = regexp.MustCompile(`\*([^*\n]+?)\*|\b_([^_\n]+?)_\b`)
+	htmlStrikeRegex    = regexp.MustCompile(`~~(.+?)~~`)
+	htmlHeaderRegex    = regexp.MustCompile(`^(#{1,3}) (.+)$`)
+)
+
+// renderDiscordHTML converts Discord markdown to HTML, escaping everything else.
+// Mentions, custom emoji and timestamps are resolved with r.
+func renderDiscordHTML(content string, mentions []discord.User, r *Resolver) template.HTML {
+	var sb strings.Builder
+
+	for _, seg := range splitCode(content) {
+		switch seg.kind {
+		case segmentCodeBlock:
+			fmt.Fprintf(&sb, `<pre class="codeblock"><code data-lang="%s">%s</code></pre>`, html.EscapeString(seg.lang), html.EscapeString(seg.text))
+		case segmentInlineCode:
+			fmt.Fprintf(&sb, "<code>%s</code>", html.EscapeString(seg.text))
+		default:
+			sb.WriteString(renderDiscordHTMLText(seg.text, mentions, r))
+		}
+	}
+
+	return template.HTML(sb.String())
+}
+
+// renderDiscordHTMLText formats a text segment: mentions, links, emphasis, spoilers, headers and quotes
+func renderDiscordHTMLText(text string, mentions []discord.User, r *Resolver) string {
+	lines := strings.Split(text, "\n")
+	out := make([]string, 0, len(lines))
+
+	for _, line := range lines {
+		quote := false
+		if rest, ok := strings.CutPrefix(line, "> "); ok {
+			quote = true
+			line = rest
+		}
+
+		// Tokens and links are swapped for placeholders so emphasis can't break their names and URLs
+		var tokens []string
+		line = r.replaceTokens(line, mentions, func(t contentToken) string {
+			tokens = append(tokens, htmlToken(t))
+			return fmt.Sprintf("\x01%d\x01", len(tokens)-1)
+		})
+
+		var links []string
+		line = htmlURLRegex.ReplaceAllStringFunc(line, func(u string) string {
+			links = append(links, u)
+			return fmt.Sprintf("\x00%d\x00", len(links)-1)
+		})
+
+		line = html.EscapeString(line)
+		line = htmlSpoilerRegex.ReplaceAllString(line, `<span class="spoiler">$1</span>`)
+		line = htmlBoldRegex.ReplaceAllString(line, "<strong>$1</strong>")
+		line = htmlUnderlineRegex.ReplaceAllString(line, "<u>$1</u>")
+		line = htmlItalicRegex.ReplaceAllString(line, "<em>$1$2</em>")
+		line = htmlStrikeRegex.ReplaceAllString(line, "<s>$1</s>")
+
+		if m := htmlHeaderRegex.FindStringSubmatch(line); m != nil {
+			line = fmt.Sprintf(`<span class="h%d">%s</span>`, len(m[1]), m[2])
+		}
+
+		for i, u := range links {
+			escaped := html.EscapeString(u)
+			line = strings.Replace(line, fmt.Sprintf("\x00%d\x00", i), fmt.Sprintf(`<a href="%s">%s</a>`, escaped, escaped), 1)
+		}
+		for i, t := range tokens {
+			line = strings.Replace(line, fmt.Sprintf("\x01%d\x01", i), t, 1)
+		}
+
+		if quote {
+			line = `<span class="quote">` + line + `</span>`
+		}
+		out = append(out, line)
+	}
+
+	return strings.Join(out, "<br>")
+}
+
+// htmlToken renders a resolved token: mentions highlighted, custom emoji as images
+// and timestamps with their full date on hover
+func htmlToken(t contentToken) string {
+	text := html.EscapeString(t.text)
+	switch {
+	case !t.known:
+		return text
+	case t.kind == tokenEmoji:
+		return fmt.Sprintf(`<img class="emoji" src="%s" alt="%s" title="%s">`, html.EscapeString(t.emoji.URL()), text, text)
+	case t.kind == tokenTimestamp:
+		return fmt.Sprintf(`<span class="timestamp" title="%s">%s</span>`, html.EscapeString(t.title), text)
+	default:
+		return `<span class="mention">` + text + `</span>`
+	}
+}
+
+var htmlTemplates = template.Must(template.New("html").Parse(`
+{{define "header"}}<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { margin: 0; background: #313338; color: #dbdee1; font: 16px/1.375 "gg sans", "Noto Sans", "Helvetica Neue", Helvetica, Arial, sans-serif; }
+header { padding: 16px 24px; border-bottom: 1px solid #1f2023; }
+header h1 { margin: 0; font-size: 20px; color: #f2f3f5; }
+header p { margin: 4px 0 0; color: #949ba4; font-size: 13px; }
+header .tags { display: flex; flex-wrap: wrap; gap: 4px; margin-top: 6px; }
+header .tag { background: #2b2d31; border-radius: 8px; padding: 2px 8px; font-size: 12px; }
+main { padding: 8px 0 24px; }
+a { color: #00a8fc; text-decoration: none; }
+a:hover { text-decoration: underline; }
+.group { display: flex; padding: 8px 24px 2px 16px; margin-top: 12px; }
+.group:hover, .message:hover { background: #2e3035; }
+.avatar { width: 40px; height: 40px; border-radius: 50%; margin-right: 16px; flex-shrink: 0; }
+.body { min-width: 0; flex: 1; }
+.author { color: #f2f3f5; font-weight: 500; margin-right: 6px; }
+.stamp, .edited { color: #949ba4; font-size: 12px; }
+.pins { margin: 16px 24px; padding: 8px 16px; background: #2b2d31; border-radius: 8px; }
+.pins h2 { margin: 4px 0 8px; font-size: 16px; color: #f2f3f5; }
+.pin { padding: 4px 0; }
+.pin .stamp { margin: 0 8px; }
+.pin .jump { font-size: 12px; }
+.message { position: relative; padding: 1px 0; word-wrap: break-word; white-space: normal; }
+.system { padding: 4px 24px 4px 72px; color: #949ba4; font-style: italic; }
+.reply { display: flex; align-items: center; gap: 4px; color: #b5bac1; font-size: 14px; margin-bottom: 2px; }
+.reply img { width: 16px; height: 16px; border-radius: 50%; }
+.reply .author { font-size: 14px; }
+code { background: #2b2d31; border-radius: 3px; padding: 0 3px; font-family: Consolas, "Courier New", monospace; font-size: 85%; }
+pre.codeblock { background: #2b2d31; border: 1px solid #1e1f22; border-radius: 4px; padding: 8px; margin: 4px 0; overflow-x: auto; white-space: pre-wrap; }
+pre.codeblock code { background: none; padding: 0; }
+.quote { display: block; border-left: 4px solid #4e5058; padding-left: 12px; }
+.spoiler { background: #1e1f22; color: transparent; border-radius: 3px; cursor: pointer; }
+.spoiler:hover, .spoiler:active { color: inherit; }
+.mention { background: rgba(88, 101, 242, 0.3); color: #c9cdfb; border-radius: 3px; padding: 0 2px; font-weight: 500; }
+.timestamp { background: rgba(255, 255, 255, 0.06); border-radius: 3px; padding: 0 2px; }
+img.emoji { width: 22px; height: 22px; vertical-align: bottom; }
+.h1 { font-size: 1.5em; font-weight: 700; } .h2 { font-size: 1.25em; font-weight: 700; } .h3 { font-size: 1em; font-weight: 700; }
+.attachment { margin-top: 4px; }
+.attachment img, .attachment video { max-width: 400px; max-height: 300px; border-radius: 4px; display: block; }
+.attachment .file { display: inline-block; background: #2b2d31; border: 1px solid #1e1f22; border-radius: 4px; padding: 8px 12px; }
+.attachment .size { color: #949ba4; font-size: 12px; margin-left: 8px; }
+.embed { max-width: 520px; background: #2b2d31; border-left: 4px solid #1e1f22; border-radius: 4px; padding: 8px 16px 12px 12px; margin-top: 4px; display: grid; grid-template-columns: 1fr auto; gap: 4px 16px; }
+.embed > * { grid-column: 1; }
+.embed .thumbnail { grid-column: 2; grid-row: 1 / 5; max-width: 80px; max-height: 80px; border-radius: 4px; }
+.embed .embed-author { font-size: 14px; font-weight: 600; color: #f2f3f5; }
+.embed .embed-title { font-weight: 600; color: #f2f3f5; }
+.embed .embed-description { font-size: 14px; }
+.embed .fields { display: flex; flex-wrap: wrap; gap: 8px 16px; font-size: 14px; }
+.embed .field { flex: 1 1 100%; } .embed .field.inline { flex: 1 1 30%; }
+.embed .field-name { font-weight: 600; color: #f2f3f5; }
+.embed .embed-image { max-width: 100%; border-radius: 4px; margin-top: 8px; }
+.embed .embed-footer { font-size: 12px; color: #949ba4; }
+.reactions { display: flex; flex-wrap: wrap; gap: 4px; margin-top: 4px; }
+.reaction { display: inline-flex; align-items: center; gap: 6px; background: #2b2d31; border: 1px solid #2b2d31; border-radius: 8px; padding: 2px 6px; font-size: 14px; }
+.reaction.burst { border-color: #5865f2; }
+.reaction img { width: 16px; height: 16px; }
+</style>
+</head>
+<body>
+<header><h1>{{.Title}}</h1>
+{{- if .Tags}}<div class="tags">{{range .Tags}}<span class="tag">{{.}}</span>{{end}}</div>{{end -}}
+{{- if .Subtitle}}<p>{{.Subtitle}}</p>{{end -}}
+<p>Exported {{.Generated}}</p></header>
+<main>
+{{end}}
+
+{{define "group"}}<div class="group"><img class="avatar" src="{{.Avatar}}" alt="" loading="lazy"><div class="body"><div><span class="author">{{.Author}}</span><span class="stamp">{{.Timestamp}}</span></div>
+{{end}}
+
+{{define "message"}}<div class="message" id="m{{.ID}}" title="{{.Timestamp}}">
+{{- with .Reply}}<div class="reply">{{if .Missing}}<em>Original message was deleted</em>{{else}}<img src="{{.Avatar}}" alt=""><span class="author">{{.Author}}</span><span>{{.Content}}</span>{{end}}</div>{{end -}}
+<div class="content">{{.Content}}{{if .Edited}} <span class="edited" title="{{.Edited}}">(edited)</span>{{end}}</div>
+{{- range .Attachments}}<div class="attachment">{{if .Image}}<a href="{{.URL}}"><img src="{{.URL}}" alt="{{.Filename}}" loading="lazy"></a>{{else if .Video}}<video src="{{.URL}}" controls preload="none"></video>{{else}}<span class="file"><a href="{{.URL}}">{{.Filename}}</a><span class="size">{{.Size}}</span></span>{{end}}</div>{{end -}}
+{{- range .Embeds}}<div class="embed"{{if .Color}} style="border-left-color: {{.Color}}"{{end}}>
+{{- if .Thumbnail}}<img class="thumbnail" src="{{.Thumbnail}}" alt="" loading="lazy">{{end -}}
+{{- if .Author}}<div class="embed-author">{{if .AuthorURL}}<a href="{{.AuthorURL}}">{{.Author}}</a>{{else}}{{.Author}}{{end}}</div>{{end -}}
+{{- if .Title}}<div class="embed-title">{{if .URL}}<a href="{{.URL}}">{{.Title}}</a>{{else}}{{.Title}}{{end}}</div>{{end -}}
+{{- if .Description}}<div class="embed-description">{{.Description}}</div>{{end -}}
+{{- if .Fields}}<div class="fields">{{range .Fields}}<div class="field{{if .Inline}} inline{{end}}"><div class="field-name">{{.Name}}</div><div>{{.Value}}</div></div>{{end}}</div>{{end -}}
+{{- if .Image}}<img class="embed-image" src="{{.Image}}" alt="" loading="lazy">{{end -}}
+{{- if .Footer}}<div class="embed-footer">{{.Footer}}</div>{{end -}}
+</div>{{end -}}
+{{- if .Reactions}}<div class="reactions">{{range .Reactions}}<span class="reaction{{if .Burst}} burst{{end}}"{{if .Users}} title="{{.Users}}"{{end}}>{{if .EmojiURL}}<img src="{{.EmojiURL}}" alt=":{{.Emoji}}:" title=":{{.Emoji}}:">{{else}}{{.Emoji}}{{end}} {{.Count}}</span>{{end}}</div>{{end -}}
+</div>
+{{end}}
+
+{{define "system"}}<div class="system" id="m{{.ID}}" title="{{.Timestamp}}">{{.System}} <span class="stamp">{{.Timestamp}}</span></div>
+{{end}}
+
+{{define "pins"}}<section class="pins"><h2>Pinned messages</h2>
+{{range .}}<div class="pin"><span class="author">{{.Author}}</span><span class="stamp">{{.Stamp}}</span> <a href="#m{{.ID}}">{{.Excerpt}}</a> <a class="jump" href="{{.JumpURL}}">Open in Discord</a></div>
+{{end}}</section>
+{{end}}
+
+{{define "footer"}}</main>
+</body>
+</html>
+{{end}}
+`))
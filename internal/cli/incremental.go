@@ -0,0 +1,191 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/CaptainFallaway/Discorder/internal/discord"
+)
+
+// checkpointInterval is how many messages are written between checkpoints, one API page
+const checkpointInterval = 100
+
+// IncrementalExport appends the messages of a channel newer than the last run to an export file
+// in the given format.
+//
+// The newest exported message ID is recorded in the state file after every page is written,
+// so an interrupted run resumes from the last page written rather than starting over.
+// A channel without a checkpoint (or whose output file is missing) is exported from the start
+// of opts, which must walk oldest first.
+//
+// writerOpts configure the output format; Appending is set as needed.
+// Messages are also written to any extra writers, e.g. an archive, as each page is checkpointed.
+func IncrementalExport(dc *discord.DiscordClient, channelID, format, outputPath, statePath string, opts discord.IterMessagesOptions, writerOpts WriterOptions, extra ...MessageWriter) (int, error) {
+	if err := ValidateFormat(format); err != nil {
+		return 0, err
+	}
+	if !Appendable(format) {
+		return 0, fmt.Errorf("format %s does not support incremental export", format)
+	}
+
+	state, err := LoadExportState(statePath)
+	if err != nil {
+		return 0, err
+	}
+
+	opts.OldestFirst = true
+	opts.Around = ""
+
+	checkpoint, resuming := state.Channels[channelID]
+	if resuming && checkpoint.Output != outputPath {
+		return 0, fmt.Errorf("channel %s was exported to %s, not %s; use a different --state file to export it elsewhere", channelID, checkpoint.Output, outputPath)
+	}
+	if resuming && checkpoint.Format != "" && checkpoint.Format != format {
+		return 0, fmt.Errorf("channel %s was exported as %s, not %s", channelID, checkpoint.Format, format)
+	}
+	if _, err := os.Stat(outputPath); errors.Is(err, os.ErrNotExist) {
+		resuming = false
+	}
+	if resuming {
+		opts.After = checkpoint.LastMessageID
+	}
+
+	var f *os.File
+	appending := false
+	if resuming {
+		f, appending, err = OpenForAppend(format, outputPath)
+	} else {
+		f, err = os.Create(outputPath)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error opening output file: %w", err)
+	}
+	defer f.Close()
+
+	writerOpts.Appending = appending
+	mw, err := NewMessageWriter(format, f, writerOpts)
+	if err != nil {
+		return 0, err
+	}
+	if len(extra) > 0 {
+		mw = MultiWriter(append([]MessageWriter{mw}, extra...)...)
+	}
+
+	save := func(lastID string) error {
+		state.Channels[channelID] = ChannelState{
+			LastMessageID: lastID,
+			Output:        outputPath,
+			Format:        format,
+			UpdatedAt:     time.Now().UTC(),
+		}
+		return state.Save(statePath)
+	}
+
+	// Messages are buffered a page at a time and flushed before each checkpoint,
+	// so the state file never points past what is on disk
+	batch := make([]discord.Message, 0, checkpointInterval)
+	written := 0
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		for _, m := range batch {
+			if err := mw.WriteMessage(m); err != nil {
+				return err
+			}
+		}
+		if err := flushWriter(mw); err != nil {
+			return err
+		}
+		if err := f.Sync(); err != nil {
+			return err
+		}
+		written += len(batch)
+		lastID := batch[len(batch)-1].ID
+		batch = batch[:0]
+		return save(lastID)
+	}
+
+	for message, err := range dc.IterMessages(context.Background(), channelID, opts) {
+		if err != nil {
+			flushErr := flush()
+			mw.Close()
+			return written, errors.Join(fmt.Errorf("error fetching messages: %w", err), flushErr)
+		}
+
+		batch = append(batch, message)
+		if len(batch) >= checkpointInterval {
+			if err := flush(); err != nil {
+				mw.Close()
+				return written, err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		mw.Close()
+		return written, err
+	}
+	if err := mw.Close(); err != nil {
+		return written, err
+	}
+
+	// Record the checkpoint even if nothing new arrived, so the output path is remembered
+	if !resuming && written == 0 {
+		return 0, save(opts.After)
+	}
+	return written, nil
+}
+
+// PrepareJSONArrayAppend opens a JSON array export for appending by truncating its closing bracket.
+// Exports interrupted before the bracket was written are handled too.
+// It reports whether the array already holds any messages.
+func PrepareJSONArrayAppend(path string) (*os.File, bool, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, false, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, false, err
+	}
+
+	// Only the tail of the file is needed to find the end of the array
+	size := info.Size()
+	tailSize := min(size, 4096)
+	tail := make([]byte, tailSize)
+	if _, err := f.ReadAt(tail, size-tailSize); err != nil && err != io.EOF {
+		f.Close()
+		return nil, false, err
+	}
+
+	trimmed := bytes.TrimRight(tail, " \t\r\n")
+	trimmed = bytes.TrimSuffix(trimmed, []byte("]"))
+	trimmed = bytes.TrimRight(trimmed, " \t\r\n")
+
+	end := size - tailSize + int64(len(trimmed))
+	hasMessages := len(trimmed) > 0 && trimmed[len(trimmed)-1] != '['
+
+	// An empty array ("[]") is rewritten from scratch by the writer
+	if !hasMessages {
+		end = 0
+	}
+
+	if err := f.Truncate(end); err != nil {
+		f.Close()
+		return nil, false, err
+	}
+	if _, err := f.Seek(end, io.SeekStart); err != nil {
+		f.Close()
+		return nil, false, err
+	}
+
+	return f, hasMessages, nil
+}
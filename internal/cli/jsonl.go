@@ -0,0 +1,32 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/CaptainFallaway/Discorder/internal/discord"
+)
+
+// jsonLinesWriter writes one compact JSON message object per line.
+type jsonLinesWriter struct {
+	enc *json.Encoder
+}
+
+// NewJSONLinesWriter returns a MessageWriter producing JSON Lines, suitable for jq, grep and appending.
+func NewJSONLinesWriter(w io.Writer) MessageWriter {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &jsonLinesWriter{enc: enc}
+}
+
+func (jw *jsonLinesWriter) WriteMessage(message discord.Message) error {
+	if err := jw.enc.Encode(message); err != nil {
+		return fmt.Errorf("error marshaling message %s: %w", message.ID, err)
+	}
+	return nil
+}
+
+func (jw *jsonLinesWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,262 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/CaptainFallaway/Discorder/internal/discord"
+)
+
+// FormatMarkdown renders a CommonMark transcript
+const FormatMarkdown = "markdown"
+
+// markdownWriter renders messages as a Markdown transcript with one heading per day.
+type markdownWriter struct {
+	w        io.Writer
+	opts     WriterOptions
+	resolver *Resolver
+	started  bool
+	day      string
+	prev     *discord.Message
+}
+
+// NewMarkdownWriter returns a MessageWriter producing a CommonMark transcript.
+// Messages must be written oldest first.
+func NewMarkdownWriter(w io.Writer, opts WriterOptions) MessageWriter {
+	resolver := opts.Resolver
+	if resolver == nil {
+		resolver = NewResolver()
+	}
+	return &markdownWriter{w: w, opts: opts, resolver: resolver}
+}
+
+func (mw *markdownWriter) WriteMessage(message discord.Message) error {
+	var sb strings.Builder
+
+	if !mw.started {
+		mw.started = true
+		if mw.opts.Title != "" {
+			fmt.Fprintf(&sb, "# %s\n\n", escapeMarkdown(mw.opts.Title))
+		}
+		if len(mw.opts.Tags) > 0 {
+			tags := make([]string, 0, len(mw.opts.Tags))
+			for _, t := range mw.opts.Tags {
+				tags = append(tags, "`"+strings.ReplaceAll(t, "`", "'")+"`")
+			}
+			fmt.Fprintf(&sb, "Tags: %s\n\n", strings.Join(tags, " "))
+		}
+		if mw.opts.Subtitle != "" {
+			fmt.Fprintf(&sb, "_%s_\n\n", escapeMarkdown(mw.opts.Subtitle))
+		}
+		if len(mw.opts.Pins) > 0 {
+			sb.WriteString("## Pinned messages\n\n")
+			for _, m := range mw.opts.Pins {
+				fmt.Fprintf(&sb, "- **%s** — %s: %s ([jump](%s))\n", escapeMarkdown(displayName(m.Author)), FormatTime(m.Timestamp), escapeMarkdown(mw.resolver.resolveText(pinExcerpt(m, 100), m.Mentions)), markdownURL(m.JumpURL()))
+			}
+			sb.WriteString("\n")
+		}
+	}
+
+	mw.resolver.Observe(message)
+	ts, _ := time.Parse(time.RFC3339, message.Timestamp)
+	if day := ts.Format("2006-01-02"); day != mw.day {
+		mw.day = day
+		mw.prev = nil
+		fmt.Fprintf(&sb, "## %s\n\n", ts.Format("Monday, 2 January 2006"))
+	}
+
+	switch {
+	case isSystemMessage(message):
+		fmt.Fprintf(&sb, "*%s — %s*\n\n", escapeMarkdown(systemMessageText(message, displayName)), formatClock(message.Timestamp))
+		mw.prev = nil
+		_, err := io.WriteString(mw.w, sb.String())
+		return err
+	case !sameGroup(mw.prev, message):
+		fmt.Fprintf(&sb, "**%s** — %s\n\n", escapeMarkdown(displayName(message.Author)), formatClock(message.Timestamp))
+	}
+	mw.prev = &message
+
+	if ref := message.ReferencedMessage; ref != nil {
+		// Replies are quoted on a single line, so line breaks in the original are collapsed
+		quoted := translateDiscordMarkdown(excerpt(ref.Content, 200), ref.Mentions, mw.resolver)
+		quoted = strings.Join(strings.Fields(quoted), " ")
+		fmt.Fprintf(&sb, "> **%s**: %s\n\n", escapeMarkdown(displayName(ref.Author)), quoted)
+	} else if message.MessageReference != nil && message.Type == discord.MessageReply {
+		sb.WriteString("> *Original message was deleted*\n\n")
+	}
+
+	var body []string
+	if message.Content != "" {
+		content := translateDiscordMarkdown(message.Content, message.Mentions, mw.resolver)
+		if message.IsEdited() {
+			content += " *(edited)*"
+		}
+		body = append(body, content)
+	}
+
+	for _, a := range message.Attachments {
+		link := fmt.Sprintf("[%s](%s) (%s)", escapeMarkdown(a.Filename), markdownURL(a.URL), formatBytes(a.Size))
+		if strings.HasPrefix(a.ContentType, "image/") {
+			link = "!" + link
+		}
+		body = append(body, link)
+	}
+
+	for _, e := range message.Embeds {
+		body = append(body, markdownEmbed(e, mw.resolver))
+	}
+
+	if len(message.Reactions) > 0 {
+		reactions := make([]string, 0, len(message.Reactions))
+		for _, r := range message.Reactions {
+			name := r.Emoji.Name
+			if r.Emoji.ID != "" {
+				name = ":" + name + ":"
+			}
+			reaction := fmt.Sprintf("%s %d", name, r.Count)
+			if users := reactorNames(r); len(users) > 0 {
+				reaction += " (" + escapeMarkdown(strings.Join(users, ", ")) + ")"
+			}
+			reactions = append(reactions, reaction)
+		}
+		body = append(body, "*Reactions: "+strings.Join(reactions, ", ")+"*")
+	}
+
+	for _, part := range body {
+		sb.WriteString(part)
+		sb.WriteString("\n\n")
+	}
+
+	_, err := io.WriteString(mw.w, sb.String())
+	return err
+}
+
+func (mw *markdownWriter) Close() error {
+	return nil
+}
+
+// markdownEmbed renders an embed as a block quote
+func markdownEmbed(e discord.Embed, r *Resolver) string {
+	var lines []string
+
+	if e.Author != nil && e.Author.Name != "" {
+		lines = append(lines, escapeMarkdown(e.Author.Name))
+	}
+	if e.Title != "" {
+		title := "**" + escapeMarkdown(e.Title) + "**"
+		if e.URL != "" {
+			title = fmt.Sprintf("**[%s](%s)**", escapeMarkdown(e.Title), markdownURL(e.URL))
+		}
+		lines = append(lines, title)
+	} else if e.URL != "" && e.Type != "rich" {
+		lines = append(lines, markdownURL(e.URL))
+	}
+	if e.Description != "" {
+		lines = append(lines, strings.Split(translateDiscordMarkdown(e.Description, nil, r), "\n")...)
+	}
+	for _, f := range e.Fields {
+		lines = append(lines, fmt.Sprintf("**%s**: %s", escapeMarkdown(f.Name), translateDiscordMarkdown(f.Value, nil, r)))
+	}
+	if e.Image != nil && e.Image.URL != "" {
+		lines = append(lines, fmt.Sprintf("![](%s)", markdownURL(e.Image.URL)))
+	}
+	if e.Footer != nil && e.Footer.Text != "" {
+		lines = append(lines, "*"+escapeMarkdown(e.Footer.Text)+"*")
+	}
+
+	for i, line := range lines {
+		lines[i] = "> " + line
+	}
+	return strings.Join(lines, "\n")
+}
+
+var (
+	mdSpoilerRegex   = regexp.MustCompile(`\|\|(.+?)\|\|`)
+	mdUnderlineRegex = regexp.MustCompile(`__(.+?)__`)
+	mdSubtextRegex   = regexp.MustCompile(`(?m)^-# (.+)$`)
+	mdAngleReplacer  = strings.NewReplacer("<", `\<`, ">", `\>`)
+	mdEscapeReplacer = strings.NewReplacer(`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`, "|", `\|`, "~", `\~`)
+)
+
+// translateDiscordMarkdown converts Discord's markdown dialect to CommonMark:
+// spoilers and underline become inline HTML, subtext becomes <sub>, and mentions, custom emoji
+// and timestamps are resolved with r. HTML in the content is escaped so it shows as typed.
+// Code is passed through untouched.
+func translateDiscordMarkdown(content string, mentions []discord.User, r *Resolver) string {
+	var sb strings.Builder
+
+	for _, seg := range splitCode(content) {
+		switch seg.kind {
+		case segmentCodeBlock:
+			text := seg.text
+			if !strings.HasSuffix(text, "\n") {
+				text += "\n"
+			}
+			fmt.Fprintf(&sb, "\n```%s\n%s```\n", seg.lang, text)
+		case segmentInlineCode:
+			if strings.Contains(seg.text, "`") {
+				fmt.Fprintf(&sb, "`` %s ``", seg.text)
+			} else {
+				fmt.Fprintf(&sb, "`%s`", seg.text)
+			}
+		default:
+			// Tokens are swapped for placeholders so their names aren't escaped twice or taken for markup
+			var tokens []string
+			text := r.replaceTokens(seg.text, mentions, func(t contentToken) string {
+				tokens = append(tokens, markdownToken(t))
+				return fmt.Sprintf("\x01%d\x01", len(tokens)-1)
+			})
+			text = escapeAngleBrackets(text)
+			text = mdSpoilerRegex.ReplaceAllString(text, `<span class="spoiler">$1</span>`)
+			text = mdUnderlineRegex.ReplaceAllString(text, "<u>$1</u>")
+			text = mdSubtextRegex.ReplaceAllString(text, "<sub>$1</sub>")
+			for i, t := range tokens {
+				text = strings.Replace(text, fmt.Sprintf("\x01%d\x01", i), t, 1)
+			}
+			// Discord keeps single line breaks, CommonMark needs a hard break
+			text = strings.ReplaceAll(text, "\n", "  \n")
+			sb.WriteString(text)
+		}
+	}
+
+	return strings.TrimSpace(sb.String())
+}
+
+// escapeAngleBrackets escapes < and > so HTML in message content isn't rendered.
+// A > starting a line is Discord's quote marker, which CommonMark shares, and is kept.
+func escapeAngleBrackets(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		quote := ""
+		for _, marker := range []string{">>> ", "> "} {
+			if rest, ok := strings.CutPrefix(line, marker); ok {
+				quote, line = marker, rest
+				break
+			}
+		}
+		lines[i] = quote + mdAngleReplacer.Replace(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// markdownToken renders a resolved token: mentions in bold, unresolved ones as plain text
+func markdownToken(t contentToken) string {
+	text := escapeMarkdown(t.text)
+	if t.known && (t.kind == tokenUser || t.kind == tokenChannel || t.kind == tokenRole) {
+		return "**" + text + "**"
+	}
+	return text
+}
+
+// escapeMarkdown escapes characters with meaning in CommonMark, for names and titles
+func escapeMarkdown(text string) string {
+	return mdEscapeReplacer.Replace(text)
+}
+
+// markdownURL makes a URL safe to use as a link destination
+func markdownURL(u string) string {
+	return strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29").Replace(u)
+}
@@ -0,0 +1,29 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/CaptainFallaway/Discorder/internal/discord"
+)
+
+func TestTranslateDiscordMarkdown(t *testing.T) {
+	alice := discord.User{ID: "1", Username: "alice", GlobalName: "<Alice>"}
+
+	tests := []struct {
+		name, in, want string
+	}{
+		{"html is escaped", `<script>alert(1)</script> & <b>bold</b>`, `\<script\>alert(1)\</script\> & \<b\>bold\</b\>`},
+		{"quotes are kept", "> quoted <i>\nnext", "> quoted \\<i\\>  \nnext"},
+		{"spoilers are inline", "see ||the ending|| here", `see <span class="spoiler">the ending</span> here`},
+		{"underline and subtext", "__under__\n-# small", "<u>under</u>  \n<sub>small</sub>"},
+		{"mentions", "hi <@1> and <@2>", `hi **@\<Alice\>** and @2`},
+		{"code is untouched", "`<b>` and <b>", "`<b>` and \\<b\\>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := translateDiscordMarkdown(tt.in, []discord.User{alice}, nil); got != tt.want {
+				t.Errorf("got  %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
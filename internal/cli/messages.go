@@ -3,34 +3,95 @@ package cli
 import (
 	"context"
 	"fmt"
-	"slices"
+	"io"
+	"time"
 
 	"github.com/CaptainFallaway/Discorder/internal/discord"
+	"github.com/CaptainFallaway/Discorder/internal/snowflake"
 )
 
-func GetAllMessages(dc *discord.DiscordClient, channelID string) ([]map[string]any, error) {
-	allMessages := make([]map[string]any, 0, 100)
-	var before string
+// GetAllMessages fetches every message of a channel, oldest first.
+// Prefer StreamMessagesJSON for large channels, as this holds all messages in memory.
+func GetAllMessages(dc *discord.DiscordClient, channelID string) ([]discord.Message, error) {
+	allMessages := make([]discord.Message, 0, 100)
 
-	for {
-		messages, err := dc.GetMessages(context.Background(), channelID, before)
+	for message, err := range dc.IterMessages(context.Background(), channelID, discord.IterMessagesOptions{OldestFirst: true}) {
 		if err != nil {
 			return nil, fmt.Errorf("error fetching messages: %w", err)
 		}
+		allMessages = append(allMessages, message)
+	}
+
+	return allMessages, nil
+}
 
-		if len(messages) == 0 {
-			break
+// ChannelTitle returns a human-readable title for a channel, e.g. for document headings.
+// It falls back to the channel ID if the channel has no name, e.g. when it couldn't be fetched.
+func ChannelTitle(channel discord.Channel) string {
+	switch channel.Type {
+	case discord.ChannelDM, discord.ChannelGroupDM:
+		return dmTitle(channel)
+	default:
+		if channel.Name == "" {
+			return "Channel " + channel.ID
 		}
+		return "#" + channel.Name
+	}
+}
 
-		allMessages = append(allMessages, messages...)
-		before = messages[len(messages)-1]["id"].(string) // Use the last message's ID for the next request
+// StreamMessagesJSON writes the selected messages of a channel to w as a pretty JSON array,
+// writing each page as it arrives instead of waiting for the whole channel.
+func StreamMessagesJSON(dc *discord.DiscordClient, channelID string, opts discord.IterMessagesOptions, w io.Writer) error {
+	return StreamMessages(dc, channelID, opts, NewJSONArrayWriter(w, true, false))
+}
+
+// StreamMessages writes the selected messages of a channel to mw as they arrive.
+// The writer is closed even if fetching fails, so partial output remains well-formed.
+func StreamMessages(dc *discord.DiscordClient, channelID string, opts discord.IterMessagesOptions, mw MessageWriter) error {
+	for message, err := range dc.IterMessages(context.Background(), channelID, opts) {
+		if err != nil {
+			mw.Close()
+			return fmt.Errorf("error fetching messages: %w", err)
+		}
 
-		if len(messages) < 100 {
-			break
+		if err := mw.WriteMessage(message); err != nil {
+			mw.Close()
+			return err
 		}
 	}
 
-	slices.Reverse(allMessages)
+	return mw.Close()
+}
 
-	return allMessages, nil
+// ParseSinceBound converts a --since value to the snowflake ID messages must come after.
+// It accepts a snowflake ID, an RFC3339 timestamp or a plain date (YYYY-MM-DD, UTC).
+func ParseSinceBound(value string) (string, error) {
+	return parseMessageBound(value, false)
+}
+
+// ParseUntilBound converts an --until value to the snowflake ID messages must come before.
+// It accepts the same values as ParseSinceBound; a plain date includes the whole of that day.
+func ParseUntilBound(value string) (string, error) {
+	return parseMessageBound(value, true)
+}
+
+func parseMessageBound(value string, endOfDay bool) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	if snowflake.Valid(value) {
+		return value, nil
+	}
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			if endOfDay && layout == "2006-01-02" {
+				t = t.AddDate(0, 0, 1)
+			}
+			return discord.SnowflakeFromTime(t), nil
+		}
+	}
+
+	return "", fmt.Errorf("invalid date or snowflake %q, expected e.g. 2025-01-02, 2025-01-02T15:04:05Z or a message ID", value)
 }
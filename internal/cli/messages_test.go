@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/CaptainFallaway/Discorder/internal/discord"
+	"github.com/CaptainFallaway/Discorder/internal/discordtest"
+)
+
+func TestGetAllMessages(t *testing.T) {
+	s := discordtest.NewServer()
+	defer s.Close()
+
+	// Messages 1000 to 1249, two and a half pages, with a rate limit on the way
+	alice := discordtest.NewUser("1", "alice", "Alice")
+	s.AddMessages("100", discordtest.GenerateMessages("100", alice, 1000, 250)...)
+	s.RateLimitNext(1, time.Millisecond, false)
+
+	messages, err := GetAllMessages(s.Client(), "100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 250 {
+		t.Fatalf("got %d messages, want 250", len(messages))
+	}
+	if messages[0].ID != "1000" || messages[249].ID != "1249" {
+		t.Errorf("messages run from %s to %s, want oldest first from 1000 to 1249", messages[0].ID, messages[249].ID)
+	}
+	if n := s.RequestCount(); n != 4 {
+		t.Errorf("made %d requests, want 3 pages and a rate limited retry", n)
+	}
+}
+
+func TestParseMessageBounds(t *testing.T) {
+	day := func(d int) string { return discord.SnowflakeFromTime(time.Date(2025, 2, d, 0, 0, 0, 0, time.UTC)) }
+	tests := []struct {
+		name  string
+		parse func(string) (string, error)
+		value string
+		want  string
+	}{
+		{"since date", ParseSinceBound, "2025-02-01", day(1)},
+		{"until date includes the day", ParseUntilBound, "2025-02-01", day(2)},
+		{"until time", ParseUntilBound, "2025-02-01T00:00:00Z", day(1)},
+		{"until minute", ParseUntilBound, "2025-02-01T00:00", day(1)},
+		{"snowflake", ParseUntilBound, "1234", "1234"},
+		{"empty", ParseSinceBound, "", ""},
+	}
+	for _, tt := range tests {
+		got, err := tt.parse(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+
+	// A message late on the named day is within --until
+	late := discord.SnowflakeFromTime(time.Date(2025, 2, 1, 23, 59, 0, 0, time.UTC))
+	until, _ := ParseUntilBound("2025-02-01")
+	if discord.CompareIDs(late, until) >= 0 {
+		t.Errorf("message %s from late on 2025-02-01 is not before --until 2025-02-01 (%s)", late, until)
+	}
+
+	if _, err := ParseUntilBound("yesterday"); err == nil {
+		t.Error("parsed an invalid bound")
+	}
+}
@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pterm/pterm"
+
+	"github.com/CaptainFallaway/Discorder/internal/discord"
+)
+
+// ChannelPins returns the pinned messages of a channel, most recently pinned first,
+// with their guild set so their jump links work. guildID is empty for DMs.
+func ChannelPins(dc *discord.DiscordClient, guildID, channelID string) ([]discord.Message, error) {
+	pins, err := dc.GetPinnedMessages(context.Background(), channelID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range pins {
+		if pins[i].GuildID == "" {
+			pins[i].GuildID = guildID
+		}
+	}
+	return pins, nil
+}
+
+// PrintPins prints the pinned messages of a channel.
+func PrintPins(dc *discord.DiscordClient, channelID string) error {
+	channel, err := dc.GetChannel(context.Background(), channelID)
+	if err != nil {
+		return fmt.Errorf("failed to get channel: %w", err)
+	}
+
+	pins, err := ChannelPins(dc, channel.GuildID, channelID)
+	if err != nil {
+		return fmt.Errorf("failed to get pinned messages: %w", err)
+	}
+
+	if len(pins) == 0 {
+		fmt.Println("No pinned messages found.")
+		return nil
+	}
+
+	table := [][]string{{"Author", "Date", "Message", "Link"}}
+	for _, m := range pins {
+		table = append(table, []string{m.Author.GetName(), FormatTime(m.Timestamp), pinExcerpt(m, 80), m.JumpURL()})
+	}
+
+	fmt.Printf("Found %d pinned messages:\n\n", len(pins))
+	pterm.DefaultTable.WithHasHeader().WithData(table).Render()
+	return nil
+}
+
+// pinExcerpt returns a single-line excerpt of a pinned message
+func pinExcerpt(m discord.Message, n int) string {
+	text := excerpt(strings.Join(strings.Fields(m.Content), " "), n)
+	if len(m.Attachments) > 0 {
+		text = strings.TrimSpace(fmt.Sprintf("%s [%d attachment(s)]", text, len(m.Attachments)))
+	}
+	return text
+}
@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/CaptainFallaway/Discorder/internal/discord"
+)
+
+// reactorsWriter fills in who reacted to each message before passing it on.
+type reactorsWriter struct {
+	next MessageWriter
+	dc   *discord.DiscordClient
+}
+
+// NewReactorsWriter returns a MessageWriter that fetches the users behind every reaction,
+// normal and burst, and records them in the reaction's Users and BurstUsers before writing
+// the message to next. Reactions that can't be fetched keep only their counts and a warning is printed.
+func NewReactorsWriter(next MessageWriter, dc *discord.DiscordClient) MessageWriter {
+	return &reactorsWriter{next: next, dc: dc}
+}
+
+func (rw *reactorsWriter) WriteMessage(message discord.Message) error {
+	if len(message.Reactions) > 0 {
+		// Copy so the caller's message isn't modified
+		message.Reactions = append([]discord.Reaction(nil), message.Reactions...)
+		for i := range message.Reactions {
+			rw.fetch(message, &message.Reactions[i])
+		}
+	}
+	return rw.next.WriteMessage(message)
+}
+
+func (rw *reactorsWriter) Flush() error {
+	return flushWriter(rw.next)
+}
+
+func (rw *reactorsWriter) Close() error {
+	return rw.next.Close()
+}
+
+// fetch fills in the users of a reaction
+func (rw *reactorsWriter) fetch(message discord.Message, r *discord.Reaction) {
+	ctx := context.Background()
+
+	// Reactions without count details predate burst reactions, so are all normal
+	normal := r.CountDetails.Normal > 0 || (r.CountDetails.Burst == 0 && r.Count > 0)
+	if normal {
+		users, err := rw.dc.GetReactionUsers(ctx, message.ChannelID, message.ID, r.Emoji, discord.ReactionTypeNormal)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to get %s reactions of message %s: %v\n", r.Emoji.Name, message.ID, err)
+		}
+		r.Users = users
+	}
+
+	if r.CountDetails.Burst > 0 {
+		users, err := rw.dc.GetReactionUsers(ctx, message.ChannelID, message.ID, r.Emoji, discord.ReactionTypeBurst)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to get %s burst reactions of message %s: %v\n", r.Emoji.Name, message.ID, err)
+		}
+		r.BurstUsers = users
+	}
+}
+
+// reactorNames lists the names of everyone who reacted, normally or with a burst reaction
+func reactorNames(r discord.Reaction) []string {
+	names := make([]string, 0, len(r.Users)+len(r.BurstUsers))
+	for _, u := range r.Users {
+		names = append(names, displayName(u))
+	}
+	for _, u := range r.BurstUsers {
+		names = append(names, displayName(u))
+	}
+	return names
+}
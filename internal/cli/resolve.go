@@ -0,0 +1,243 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/CaptainFallaway/Discorder/internal/discord"
+)
+
+// Resolver looks up the names behind the mention tokens in message content:
+// users, channels and roles. Users are cached as messages are written, so
+// mentions of someone who spoke earlier resolve even without mention data.
+type Resolver struct {
+	mu       sync.Mutex
+	users    map[string]discord.User
+	channels map[string]discord.Channel
+	roles    map[string]discord.Role
+}
+
+// NewResolver returns an empty resolver.
+func NewResolver() *Resolver {
+	return &Resolver{
+		users:    make(map[string]discord.User),
+		channels: make(map[string]discord.Channel),
+		roles:    make(map[string]discord.Role),
+	}
+}
+
+// LoadResolver returns a resolver knowing a channel and, for guild channels, the guild's
+// channels and roles. Lookups that fail leave their tokens unresolved rather than failing.
+func LoadResolver(dc *discord.DiscordClient, channel discord.Channel) *Resolver {
+	r := NewResolver()
+	r.AddChannels(channel)
+	r.AddUsers(channel.Recipients...)
+
+	if channel.GuildID != "" {
+		r.LoadGuild(dc, channel.GuildID)
+	}
+	return r
+}
+
+// LoadGuild adds a guild's channels and roles to the resolver.
+func (r *Resolver) LoadGuild(dc *discord.DiscordClient, guildID string) {
+	ctx := context.Background()
+
+	channels, err := dc.GetGuildChannels(ctx, guildID)
+	if err == nil {
+		r.AddChannels(channels...)
+	} else if !discord.IsMissingAccess(err) {
+		fmt.Fprintf(os.Stderr, "Warning: failed to get guild channels for mentions: %v\n", err)
+	}
+
+	r.loadRoles(ctx, dc, guildID)
+}
+
+// loadRoles adds a guild's roles to the resolver
+func (r *Resolver) loadRoles(ctx context.Context, dc *discord.DiscordClient, guildID string) {
+	roles, err := dc.GetGuildRoles(ctx, guildID)
+	if err == nil {
+		r.AddRoles(roles...)
+	} else if !discord.IsMissingAccess(err) {
+		fmt.Fprintf(os.Stderr, "Warning: failed to get guild roles for mentions: %v\n", err)
+	}
+}
+
+// AddUsers makes users resolvable.
+func (r *Resolver) AddUsers(users ...discord.User) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, u := range users {
+		if u.ID != "" {
+			r.users[u.ID] = u
+		}
+	}
+}
+
+// AddChannels makes channels resolvable.
+func (r *Resolver) AddChannels(channels ...discord.Channel) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range channels {
+		r.channels[c.ID] = c
+	}
+}
+
+// AddRoles makes roles resolvable.
+func (r *Resolver) AddRoles(roles ...discord.Role) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, role := range roles {
+		r.roles[role.ID] = role
+	}
+}
+
+// Observe caches the users a message carries: its author, mentions and the replied-to author.
+func (r *Resolver) Observe(message discord.Message) {
+	r.AddUsers(message.Author)
+	r.AddUsers(message.Mentions...)
+	if ref := message.ReferencedMessage; ref != nil {
+		r.AddUsers(ref.Author)
+		r.AddUsers(ref.Mentions...)
+	}
+}
+
+// Kinds of content tokens
+const (
+	tokenUser = iota
+	tokenChannel
+	tokenRole
+	tokenCommand
+	tokenEmoji
+	tokenTimestamp
+)
+
+// contentToken is a resolved mention, custom emoji or timestamp in message content.
+type contentToken struct {
+	kind int
+	// text is the readable form, e.g. "@name", "#channel", ":emoji:" or a formatted date
+	text string
+	// known is false when the ID couldn't be resolved and text falls back to it
+	known bool
+	// emoji is set for custom emoji
+	emoji discord.Emoji
+	// title is the full date of a timestamp
+	title string
+}
+
+// contentTokenRegex matches user, channel and role mentions, slash command mentions,
+// custom emoji and timestamps
+var contentTokenRegex = regexp.MustCompile(`<(@!?|#|@&)(\d+)>|</([\w -]+):(\d+)>|<(a?):(\w+):(\d+)>|<t:(-?\d+)(?::([tTdDfFR]))?>`)
+
+// replaceTokens replaces the tokens in text with their rendering. Mentions are resolved from
+// the message's mentions first, then from what the resolver knows. A nil resolver only
+// uses the mentions.
+func (r *Resolver) replaceTokens(text string, mentions []discord.User, render func(contentToken) string) string {
+	return contentTokenRegex.ReplaceAllStringFunc(text, func(s string) string {
+		return render(r.token(contentTokenRegex.FindStringSubmatch(s), mentions))
+	})
+}
+
+// resolveText replaces the tokens in text with their plain readable form.
+func (r *Resolver) resolveText(text string, mentions []discord.User) string {
+	return r.replaceTokens(text, mentions, func(t contentToken) string {
+		return t.text
+	})
+}
+
+func (r *Resolver) token(m []string, mentions []discord.User) contentToken {
+	switch {
+	case m[1] != "":
+		id := m[2]
+		switch m[1] {
+		case "#":
+			if c, ok := r.channel(id); ok && c.Name != "" {
+				return contentToken{kind: tokenChannel, text: "#" + c.Name, known: true}
+			}
+			return contentToken{kind: tokenChannel, text: "#" + id}
+		case "@&":
+			if role, ok := r.role(id); ok {
+				return contentToken{kind: tokenRole, text: "@" + role.Name, known: true}
+			}
+			return contentToken{kind: tokenRole, text: "@" + id}
+		default:
+			for _, u := range mentions {
+				if u.ID == id {
+					return contentToken{kind: tokenUser, text: "@" + displayName(u), known: true}
+				}
+			}
+			if u, ok := r.user(id); ok {
+				return contentToken{kind: tokenUser, text: "@" + displayName(u), known: true}
+			}
+			return contentToken{kind: tokenUser, text: "@" + id}
+		}
+	case m[3] != "":
+		return contentToken{kind: tokenCommand, text: "/" + m[3], known: true}
+	case m[6] != "":
+		emoji := discord.Emoji{ID: m[7], Name: m[6], Animated: m[5] == "a"}
+		return contentToken{kind: tokenEmoji, text: ":" + m[6] + ":", known: true, emoji: emoji}
+	default:
+		secs, err := strconv.ParseInt(m[8], 10, 64)
+		if err != nil {
+			return contentToken{kind: tokenTimestamp, text: m[0]}
+		}
+		// In UTC like message times, so exports read the same wherever they're made
+		t := time.Unix(secs, 0).UTC()
+		return contentToken{kind: tokenTimestamp, text: formatTimestamp(t, m[9]), known: true, title: t.Format("Monday, 2 January 2006 15:04")}
+	}
+}
+
+func (r *Resolver) user(id string) (discord.User, bool) {
+	if r == nil {
+		return discord.User{}, false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[id]
+	return u, ok
+}
+
+func (r *Resolver) channel(id string) (discord.Channel, bool) {
+	if r == nil {
+		return discord.Channel{}, false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.channels[id]
+	return c, ok
+}
+
+func (r *Resolver) role(id string) (discord.Role, bool) {
+	if r == nil {
+		return discord.Role{}, false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	role, ok := r.roles[id]
+	return role, ok
+}
+
+// formatTimestamp formats a timestamp token in one of Discord's styles, f if none is given.
+// Relative timestamps (R) are written as the date they refer to, since an export outlives
+// the moment it was made in.
+func formatTimestamp(t time.Time, style string) string {
+	switch style {
+	case "t":
+		return t.Format("15:04")
+	case "T":
+		return t.Format("15:04:05")
+	case "d":
+		return t.Format("2006-01-02")
+	case "D":
+		return t.Format("2 January 2006")
+	case "F":
+		return t.Format("Monday, 2 January 2006 15:04")
+	default:
+		return t.Format("2 January 2006 15:04")
+	}
+}
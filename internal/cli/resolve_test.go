@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResolveTimestamps(t *testing.T) {
+	// Exports read the same in any zone
+	local := time.Local
+	time.Local = time.FixedZone("UTC+10", 10*60*60)
+	defer func() { time.Local = local }()
+
+	// 2025-01-02 03:04:05 UTC
+	const ts = "1735787045"
+	tests := []struct {
+		style, want string
+	}{
+		{"", "2 January 2025 03:04"},
+		{":t", "03:04"},
+		{":T", "03:04:05"},
+		{":d", "2025-01-02"},
+		{":D", "2 January 2025"},
+		{":f", "2 January 2025 03:04"},
+		{":F", "Thursday, 2 January 2025 03:04"},
+		{":R", "2 January 2025 03:04"},
+	}
+	r := NewResolver()
+	for _, tt := range tests {
+		token := "<t:" + ts + tt.style + ">"
+		if got := r.resolveText(token, nil); got != tt.want {
+			t.Errorf("%s = %q, want %q", token, got, tt.want)
+		}
+	}
+}
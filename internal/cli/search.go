@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pterm/pterm"
+
+	"github.com/CaptainFallaway/Discorder/internal/archive"
+)
+
+// ApplySearchOperators moves Discord-style operators out of the query text into filters:
+// has:attachment, has:link, from:<user>, in:<channel> and guild:<guild>.
+func ApplySearchOperators(q *archive.SearchQuery) error {
+	var words []string
+
+	for _, word := range strings.Fields(q.Text) {
+		key, value, ok := strings.Cut(word, ":")
+		if !ok || value == "" {
+			words = append(words, word)
+			continue
+		}
+
+		switch strings.ToLower(key) {
+		case "has":
+			switch strings.ToLower(value) {
+			case "attachment", "file":
+				q.HasAttachment = true
+			case "link":
+				q.HasLink = true
+			default:
+				return fmt.Errorf("unknown filter has:%s, expected has:attachment or has:link", value)
+			}
+		case "from":
+			q.Author = value
+		case "in":
+			q.Channel = value
+		case "guild":
+			q.Guild = value
+		default:
+			words = append(words, word)
+		}
+	}
+
+	q.Text = strings.Join(words, " ")
+	return nil
+}
+
+// PrintSearch searches the archive and prints the results as tables.
+// With context, each result gets its own table of the surrounding messages.
+func PrintSearch(a *archive.Archive, q archive.SearchQuery) error {
+	results, err := a.Search(context.Background(), q)
+	if err != nil {
+		return err
+	}
+
+	if len(results) == 0 {
+		fmt.Println("No messages found.")
+		return nil
+	}
+
+	fmt.Printf("Found %d messages:\n\n", len(results))
+
+	if q.Context == 0 {
+		table := [][]string{{"Date", "Channel", "Author", "Message", "Link"}}
+		for _, r := range results {
+			table = append(table, []string{FormatTime(r.Timestamp), searchChannelName(r), r.Author.GetName(), searchExcerpt(r), r.JumpURL()})
+		}
+		pterm.DefaultTable.WithHasHeader().WithData(table).Render()
+		return nil
+	}
+
+	for i, r := range results {
+		fmt.Printf("%d. %s — %s\n\n", i+1, searchChannelName(r), r.JumpURL())
+
+		table := [][]string{{"", "Date", "Author", "Message"}}
+		for _, c := range r.Before {
+			table = append(table, []string{"", FormatTime(c.Timestamp), c.Author.GetName(), searchExcerpt(c)})
+		}
+		table = append(table, []string{"»", FormatTime(r.Timestamp), r.Author.GetName(), searchExcerpt(r)})
+		for _, c := range r.After {
+			table = append(table, []string{"", FormatTime(c.Timestamp), c.Author.GetName(), searchExcerpt(c)})
+		}
+		pterm.DefaultTable.WithHasHeader().WithData(table).Render()
+		fmt.Println()
+	}
+
+	return nil
+}
+
+// searchChannelName names the channel of a result, including its guild if known
+func searchChannelName(r archive.SearchResult) string {
+	name := r.ChannelID
+	if r.ChannelName != "" {
+		name = "#" + r.ChannelName
+	}
+	if r.GuildName != "" {
+		name = fmt.Sprintf("%s (%s)", name, r.GuildName)
+	}
+	return name
+}
+
+// searchExcerpt returns a single-line excerpt of a result's content for table cells
+func searchExcerpt(r archive.SearchResult) string {
+	text := excerpt(strings.Join(strings.Fields(r.Content), " "), 100)
+	if r.Attachments > 0 {
+		text = strings.TrimSpace(fmt.Sprintf("%s [%d attachment(s)]", text, r.Attachments))
+	}
+	return text
+}
@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultStatePath is where incremental exports record their progress by default
+const DefaultStatePath = ".discorder-state.json"
+
+// ExportState records the progress of incremental exports, keyed by channel ID.
+type ExportState struct {
+	Channels map[string]ChannelState `json:"channels"`
+}
+
+// ChannelState is the checkpoint of one channel's incremental export.
+type ChannelState struct {
+	LastMessageID string    `json:"last_message_id"`
+	Output        string    `json:"output"`
+	Format        string    `json:"format,omitempty"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// LoadExportState reads the state file at path, returning an empty state if it doesn't exist.
+func LoadExportState(path string) (*ExportState, error) {
+	state := &ExportState{Channels: make(map[string]ChannelState)}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading state file: %w", err)
+	}
+
+	if err := json.Unmarshal(b, state); err != nil {
+		return nil, fmt.Errorf("error parsing state file %s: %w", path, err)
+	}
+	if state.Channels == nil {
+		state.Channels = make(map[string]ChannelState)
+	}
+	return state, nil
+}
+
+// Save writes the state to path atomically, so an interrupted run never leaves a corrupt file.
+func (s *ExportState) Save(path string) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error writing state file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing state file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing state file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("error writing state file: %w", err)
+	}
+	return nil
+}
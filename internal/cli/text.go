@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/CaptainFallaway/Discorder/internal/discord"
+)
+
+// FormatText renders an IRC-style plain text chat log
+const FormatText = "text"
+
+// textWriter writes messages as IRC-style log lines:
+//
+//	[2025-01-02 15:04] <user> content
+//	[2025-01-02 15:05] * user pinned a message
+//
+// Continuation lines of multi-line messages are indented under the content.
+type textWriter struct {
+	w        io.Writer
+	pins     []discord.Message
+	resolver *Resolver
+	started  bool
+}
+
+// NewTextWriter returns a MessageWriter producing a plain text chat log.
+// Pinned messages in opts are listed first, unless appending to an existing log.
+func NewTextWriter(w io.Writer, opts WriterOptions) MessageWriter {
+	tw := &textWriter{w: w, resolver: opts.Resolver}
+	if tw.resolver == nil {
+		tw.resolver = NewResolver()
+	}
+	if !opts.Appending {
+		tw.pins = opts.Pins
+	}
+	return tw
+}
+
+// start writes the pinned messages section once
+func (tw *textWriter) start() error {
+	if tw.started || len(tw.pins) == 0 {
+		tw.started = true
+		return nil
+	}
+	tw.started = true
+
+	var sb strings.Builder
+	sb.WriteString("--- Pinned messages ---\n")
+	for _, m := range tw.pins {
+		fmt.Fprintf(&sb, "[%s] <%s> %s\n", FormatTime(m.Timestamp), m.Author.GetName(), tw.resolver.resolveText(pinExcerpt(m, 100), m.Mentions))
+	}
+	sb.WriteString("-----------------------\n\n")
+	_, err := io.WriteString(tw.w, sb.String())
+	return err
+}
+
+func (tw *textWriter) WriteMessage(message discord.Message) error {
+	if err := tw.start(); err != nil {
+		return err
+	}
+
+	tw.resolver.Observe(message)
+	stamp := fmt.Sprintf("[%s]", FormatTime(message.Timestamp))
+
+	if isSystemMessage(message) {
+		_, err := fmt.Fprintf(tw.w, "%s * %s\n", stamp, systemMessageText(message, discord.User.GetName))
+		return err
+	}
+
+	prefix := fmt.Sprintf("%s <%s> ", stamp, message.Author.GetName())
+	indent := strings.Repeat(" ", len([]rune(prefix)))
+
+	var lines []string
+	if ref := message.ReferencedMessage; ref != nil {
+		quoted := excerpt(strings.Join(strings.Fields(tw.resolver.resolveText(ref.Content, ref.Mentions)), " "), 80)
+		lines = append(lines, fmt.Sprintf("(replying to <%s> %s)", ref.Author.GetName(), quoted))
+	}
+	if message.Content != "" {
+		lines = append(lines, strings.Split(tw.resolver.resolveText(message.Content, message.Mentions), "\n")...)
+	}
+	if message.IsEdited() {
+		if len(lines) == 0 {
+			lines = append(lines, "")
+		}
+		lines[len(lines)-1] += " (edited)"
+	}
+	for _, a := range message.Attachments {
+		lines = append(lines, a.URL)
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "")
+	}
+
+	var sb strings.Builder
+	for i, line := range lines {
+		if i == 0 {
+			sb.WriteString(prefix)
+		} else {
+			sb.WriteString(indent)
+		}
+		sb.WriteString(line)
+		sb.WriteByte('\n')
+	}
+
+	_, err := io.WriteString(tw.w, sb.String())
+	return err
+}
+
+func (tw *textWriter) Close() error {
+	return tw.start()
+}
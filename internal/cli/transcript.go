@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/CaptainFallaway/Discorder/internal/discord"
+)
+
+// groupWindow is how close in time consecutive messages by the same author must be to be grouped
+const groupWindow = 7 * time.Minute
+
+// sameGroup reports whether a message belongs under the previous message's author header
+// in rendered transcripts. Replies always start a new group.
+func sameGroup(prev *discord.Message, message discord.Message) bool {
+	if prev == nil || prev.Author.ID != message.Author.ID || message.ReferencedMessage != nil {
+		return false
+	}
+	prevTime, err1 := time.Parse(time.RFC3339, prev.Timestamp)
+	curTime, err2 := time.Parse(time.RFC3339, message.Timestamp)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	return curTime.Sub(prevTime) < groupWindow
+}
+
+// isSystemMessage reports whether a message is a system notice (join, pin, call, ...)
+// rather than something a user wrote
+func isSystemMessage(message discord.Message) bool {
+	switch message.Type {
+	case discord.MessageDefault, discord.MessageReply, discord.MessageChatInputCommand,
+		discord.MessageContextMenuCommand, discord.MessageThreadStarterMessage:
+		return false
+	default:
+		return true
+	}
+}
+
+// displayName returns the name shown for an author: global name if set, otherwise username
+func displayName(u discord.User) string {
+	if u.GlobalName != "" {
+		return u.GlobalName
+	}
+	if u.Username != "" {
+		return u.Username
+	}
+	return "Unknown User"
+}
+
+// systemMessageText describes a system message, e.g. a pin or a member joining,
+// naming users with nameOf
+func systemMessageText(message discord.Message, nameOf func(discord.User) string) string {
+	name := nameOf(message.Author)
+	switch message.Type {
+	case discord.MessageRecipientAdd:
+		if len(message.Mentions) > 0 {
+			return fmt.Sprintf("%s added %s to the group", name, nameOf(message.Mentions[0]))
+		}
+		return fmt.Sprintf("%s added someone to the group", name)
+	case discord.MessageRecipientRemove:
+		if len(message.Mentions) > 0 && message.Mentions[0].ID != message.Author.ID {
+			return fmt.Sprintf("%s removed %s from the group", name, nameOf(message.Mentions[0]))
+		}
+		return fmt.Sprintf("%s left the group", name)
+	case discord.MessageCall:
+		return fmt.Sprintf("%s started a call", name)
+	case discord.MessageChannelNameChange:
+		return fmt.Sprintf("%s changed the channel name to %s", name, message.Content)
+	case discord.MessageChannelIconChange:
+		return fmt.Sprintf("%s changed the channel icon", name)
+	case discord.MessageChannelPinnedMessage:
+		return fmt.Sprintf("%s pinned a message", name)
+	case discord.MessageUserJoin:
+		return fmt.Sprintf("%s joined the server", name)
+	case discord.MessageGuildBoost, discord.MessageGuildBoostTier1, discord.MessageGuildBoostTier2, discord.MessageGuildBoostTier3:
+		return fmt.Sprintf("%s boosted the server", name)
+	case discord.MessageChannelFollowAdd:
+		return fmt.Sprintf("%s followed %s", name, message.Content)
+	case discord.MessageThreadCreated:
+		return fmt.Sprintf("%s started a thread: %s", name, message.Content)
+	case discord.MessageAutoModerationAction:
+		return "AutoMod blocked a message"
+	case discord.MessageStageStart:
+		return fmt.Sprintf("%s started the stage %s", name, message.Content)
+	case discord.MessageStageEnd:
+		return fmt.Sprintf("%s ended the stage %s", name, message.Content)
+	case discord.MessageStageTopic:
+		return fmt.Sprintf("%s changed the stage topic to %s", name, message.Content)
+	case discord.MessagePollResult:
+		return "A poll has ended"
+	default:
+		if message.Content != "" {
+			return fmt.Sprintf("%s: %s", name, message.Content)
+		}
+		return fmt.Sprintf("%s (message type %d)", name, message.Type)
+	}
+}
+
+// formatClock returns the HH:MM part of an RFC3339 timestamp
+func formatClock(ts string) string {
+	t, err := time.Parse(time.RFC3339, ts)
+	if err != nil {
+		return ""
+	}
+	return t.Format("15:04")
+}
+
+// formatBytes returns a human-readable file size
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// excerpt shortens text to at most n runes, adding an ellipsis if it was cut
+func excerpt(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n]) + "…"
+}
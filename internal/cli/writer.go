@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hokaccha/go-prettyjson"
+
+	"github.com/CaptainFallaway/Discorder/internal/discord"
+)
+
+// MessageWriter writes messages, one at a time, in an export format.
+type MessageWriter interface {
+	// WriteMessage writes a single message
+	WriteMessage(message discord.Message) error
+	// Close finishes the document. It does not close the underlying writer.
+	Close() error
+}
+
+// Flusher is implemented by MessageWriters that buffer messages, such as the archive writer.
+type Flusher interface {
+	Flush() error
+}
+
+// flushWriter flushes mw if it buffers messages
+func flushWriter(mw MessageWriter) error {
+	if f, ok := mw.(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// jsonArrayWriter writes messages as a pretty printed JSON array.
+type jsonArrayWriter struct {
+	w         io.Writer
+	formatter *prettyjson.Formatter
+	count     int
+}
+
+// NewJSONArrayWriter returns a MessageWriter producing a pretty JSON array.
+// If appending is true the array is assumed to already be opened and hold at least one message,
+// as left by PrepareJSONArrayAppend.
+func NewJSONArrayWriter(w io.Writer, color bool, appending bool) MessageWriter {
+	formatter := prettyjson.NewFormatter()
+	formatter.DisabledColor = !color
+
+	jw := &jsonArrayWriter{w: w, formatter: formatter}
+	if appending {
+		jw.count = 1
+	}
+	return jw
+}
+
+func (jw *jsonArrayWriter) WriteMessage(message discord.Message) error {
+	b, err := jw.formatter.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("error marshaling message %s: %w", message.ID, err)
+	}
+
+	prefix := ",\n  "
+	if jw.count == 0 {
+		prefix = "[\n  "
+	}
+	// Indent the object one level so it nests inside the array
+	indented := strings.ReplaceAll(string(b), "\n", "\n  ")
+	if _, err := fmt.Fprintf(jw.w, "%s%s", prefix, indented); err != nil {
+		return err
+	}
+	jw.count++
+	return nil
+}
+
+func (jw *jsonArrayWriter) Close() error {
+	if jw.count == 0 {
+		_, err := fmt.Fprintln(jw.w, "[]")
+		return err
+	}
+	_, err := fmt.Fprintln(jw.w, "\n]")
+	return err
+}
+
+// multiWriter duplicates messages to several writers.
+type multiWriter struct {
+	writers []MessageWriter
+}
+
+// MultiWriter returns a MessageWriter that writes every message to all of the given writers,
+// e.g. to export a file and archive the same messages in one pass.
+func MultiWriter(writers ...MessageWriter) MessageWriter {
+	return &multiWriter{writers: writers}
+}
+
+func (mw *multiWriter) WriteMessage(message discord.Message) error {
+	for _, w := range mw.writers {
+		if err := w.WriteMessage(message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (mw *multiWriter) Flush() error {
+	for _, w := range mw.writers {
+		if err := flushWriter(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (mw *multiWriter) Close() error {
+	var errs []error
+	for _, w := range mw.writers {
+		if err := w.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
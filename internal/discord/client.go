@@ -1,13 +1,16 @@
 package discord
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"time"
 )
 
 const (
@@ -41,13 +44,50 @@ const (
 
 // DiscordClient represents a client for interacting with the Discord API
 type DiscordClient struct {
-	token  string
-	client *http.Client
-	debug  bool
+	token      string
+	client     *http.Client
+	debug      bool
+	logger     Logger
+	baseURL    string
+	apiVersion string
+	timeout    time.Duration
+	limiter    *rateLimiter
+	retry      RetryPolicy
 }
 
-func NewDiscordClient(token string, debug bool) *DiscordClient {
-	return &DiscordClient{token: token, client: &http.Client{}, debug: debug}
+// NewDiscordClient creates a client authenticated with the given user token.
+// Options can override the base URL, HTTP client, API version, timeout and logger.
+func NewDiscordClient(token string, debug bool, opts ...Option) *DiscordClient {
+	dc := &DiscordClient{
+		token:      token,
+		client:     &http.Client{},
+		debug:      debug,
+		logger:     stdoutLogger{},
+		baseURL:    DefaultBaseURL,
+		apiVersion: ApiVersion,
+		limiter:    newRateLimiter(),
+		retry:      DefaultRetryPolicy,
+	}
+
+	for _, opt := range opts {
+		opt(dc)
+	}
+
+	if dc.timeout > 0 {
+		// Copy so a caller-provided client isn't modified
+		client := *dc.client
+		client.Timeout = dc.timeout
+		dc.client = &client
+	}
+
+	return dc
+}
+
+// logf prints debug output if debugging is enabled
+func (dc *DiscordClient) logf(format string, args ...any) {
+	if dc.debug {
+		dc.logger.Printf(format, args...)
+	}
 }
 
 // Simple request with just method and path (context-aware)
@@ -55,37 +95,100 @@ func (dc *DiscordClient) Request(ctx context.Context, method, path string) (io.R
 	return dc.RequestWithOptions(ctx, method, path, nil, nil)
 }
 
-// Full request with queries and body (context-aware)
+// Full request with queries and body (context-aware).
+// Rate limits are honored: requests wait for their bucket to reset and 429 responses are retried.
+// Network errors and transient 5xx responses are retried according to the client's RetryPolicy.
 func (dc *DiscordClient) RequestWithOptions(ctx context.Context, method, path string, queries url.Values, body io.ReadCloser) (io.ReadCloser, error) {
-	req, err := dc.buildRequest(ctx, method, path, queries, body)
-	if err != nil {
-		return nil, err
+	// Buffer the body so it can be resent if the request is retried
+	var payload []byte
+	if body != nil {
+		b, err := io.ReadAll(body)
+		body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error reading request body: %w", err)
+		}
+		payload = b
 	}
 
-	if dc.debug {
-		fmt.Println("Making request:", req.Method, req.URL.String())
-	}
-
-	resp, err := dc.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error making request: %w", err)
-	}
-
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		// Read response body to include in error (limited)
-		defer resp.Body.Close()
-		limited := io.LimitReader(resp.Body, 8192)
-		b, _ := io.ReadAll(limited)
-		return nil, fmt.Errorf("request failed: %s: %s", resp.Status, strings.TrimSpace(string(b)))
+	rateLimited, attempts := 0, 0
+	for {
+		waited, err := dc.limiter.wait(ctx, method, path)
+		if err != nil {
+			return nil, err
+		}
+		if waited > 0 {
+			dc.logf("Rate limited, waited %s before request: %s %s", waited.Round(time.Millisecond), method, path)
+		}
+
+		var reqBody io.ReadCloser
+		if payload != nil {
+			reqBody = io.NopCloser(bytes.NewReader(payload))
+		}
+
+		req, err := dc.buildRequest(ctx, method, path, queries, reqBody)
+		if err != nil {
+			return nil, err
+		}
+
+		dc.logf("Making request: %s %s", req.Method, req.URL.String())
+
+		attempts++
+		resp, err := dc.client.Do(req)
+		if err != nil {
+			if retryableError(ctx, err) && dc.retry.canRetry(method, attempts) {
+				if err := dc.backoff(ctx, method, path, attempts, err.Error()); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			return nil, fmt.Errorf("error making request: %w", err)
+		}
+
+		dc.limiter.update(method, path, resp.Header)
+
+		if resp.StatusCode == http.StatusTooManyRequests && rateLimited < maxRateLimitRetries {
+			rateLimited++
+			// Rate limited attempts don't count towards the retry policy
+			attempts--
+			retryAfter := dc.limiter.handleTooManyRequests(resp)
+			resp.Body.Close()
+
+			dc.logf("Rate limited (429), retrying in %s: %s %s", retryAfter.Round(time.Millisecond), method, path)
+
+			if err := sleepContext(ctx, retryAfter); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if dc.retry.retryableStatus(resp.StatusCode) && dc.retry.canRetry(method, attempts) {
+			resp.Body.Close()
+			if err := dc.backoff(ctx, method, path, attempts, resp.Status); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			defer resp.Body.Close()
+			return nil, newAPIError(resp)
+		}
+
+		// Decode compressed responses if any
+		decoded, err := decodeBody(resp)
+		if err != nil {
+			// Fallback to raw body on decode error
+			return resp.Body, nil
+		}
+		return decoded, nil
 	}
+}
 
-	// Decode compressed responses if any
-	decoded, err := decodeBody(resp)
-	if err != nil {
-		// Fallback to raw body on decode error
-		return resp.Body, nil
-	}
-	return decoded, nil
+// backoff logs a transient failure and sleeps before the next attempt
+func (dc *DiscordClient) backoff(ctx context.Context, method, path string, attempts int, reason string) error {
+	delay := dc.retry.backoff(attempts)
+	dc.logf("Request failed (%s), retrying in %s (attempt %d/%d): %s %s", reason, delay.Round(time.Millisecond), attempts+1, dc.retry.MaxAttempts, method, path)
+	return sleepContext(ctx, delay)
 }
 
 // GetAllRelationships retrieves all relationships for the authenticated user
@@ -140,6 +243,22 @@ func (dc *DiscordClient) CreateDMChannel(ctx context.Context, userID string) (Ch
 	return channel, nil
 }
 
+// GetChannel retrieves a channel by its ID
+func (dc *DiscordClient) GetChannel(ctx context.Context, channelID string) (Channel, error) {
+	path := fmt.Sprintf("/channels/%s", channelID)
+	body, err := dc.Request(ctx, "GET", path)
+	if err != nil {
+		return Channel{}, fmt.Errorf("error fetching channel: %w", err)
+	}
+	defer body.Close()
+
+	var channel Channel
+	if err := json.NewDecoder(body).Decode(&channel); err != nil {
+		return Channel{}, fmt.Errorf("error parsing JSON response: %w", err)
+	}
+	return channel, nil
+}
+
 // RemoveDMChannel deletes a DM channel by its ID
 func (dc *DiscordClient) RemoveDMChannel(ctx context.Context, channelID string) error {
 	path := fmt.Sprintf("/channels/%s", channelID)
@@ -151,14 +270,28 @@ func (dc *DiscordClient) RemoveDMChannel(ctx context.Context, channelID string)
 }
 
 // GetMessages retrieves messages from a channel, paginated by the 'before' parameter
-func (dc *DiscordClient) GetMessages(ctx context.Context, channelID string, before string) ([]map[string]any, error) {
+func (dc *DiscordClient) GetMessages(ctx context.Context, channelID string, before string) ([]Message, error) {
+	return dc.GetMessagesPage(ctx, channelID, MessagePage{Before: before})
+}
+
+// GetMessagesPage retrieves a single page of messages from a channel, newest first
+func (dc *DiscordClient) GetMessagesPage(ctx context.Context, channelID string, page MessagePage) ([]Message, error) {
 	path := fmt.Sprintf("/channels/%s/messages", channelID)
 	queries := url.Values{
 		"limit": []string{MessageLimit},
 	}
 
-	if before != "" {
-		queries.Add("before", before)
+	if page.Limit > 0 {
+		queries.Set("limit", strconv.Itoa(page.Limit))
+	}
+
+	switch {
+	case page.Before != "":
+		queries.Add("before", page.Before)
+	case page.After != "":
+		queries.Add("after", page.After)
+	case page.Around != "":
+		queries.Add("around", page.Around)
 	}
 
 	body, err := dc.RequestWithOptions(ctx, "GET", path, queries, nil)
@@ -167,8 +300,24 @@ func (dc *DiscordClient) GetMessages(ctx context.Context, channelID string, befo
 	}
 	defer body.Close()
 
-	var messages []map[string]any
+	var messages []Message
+
+	if err := json.NewDecoder(body).Decode(&messages); err != nil {
+		return nil, fmt.Errorf("error parsing JSON response: %w", err)
+	}
+	return messages, nil
+}
+
+// GetPinnedMessages retrieves the pinned messages of a channel, most recently pinned first
+func (dc *DiscordClient) GetPinnedMessages(ctx context.Context, channelID string) ([]Message, error) {
+	path := fmt.Sprintf("/channels/%s/pins", channelID)
+	body, err := dc.Request(ctx, "GET", path)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching pinned messages: %w", err)
+	}
+	defer body.Close()
 
+	messages := make([]Message, 0)
 	if err := json.NewDecoder(body).Decode(&messages); err != nil {
 		return nil, fmt.Errorf("error parsing JSON response: %w", err)
 	}
@@ -205,3 +354,19 @@ func (dc *DiscordClient) GetGuildChannels(ctx context.Context, guildID string) (
 	}
 	return channels, nil
 }
+
+// GetGuildRoles retrieves the roles of a guild
+func (dc *DiscordClient) GetGuildRoles(ctx context.Context, guildID string) ([]Role, error) {
+	path := fmt.Sprintf("/guilds/%s/roles", guildID)
+	body, err := dc.Request(ctx, "GET", path)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching guild roles: %w", err)
+	}
+	defer body.Close()
+
+	roles := make([]Role, 0)
+	if err := json.NewDecoder(body).Decode(&roles); err != nil {
+		return nil, fmt.Errorf("error parsing JSON response: %w", err)
+	}
+	return roles, nil
+}
@@ -0,0 +1,175 @@
+package discord_test
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CaptainFallaway/Discorder/internal/discord"
+	"github.com/CaptainFallaway/Discorder/internal/discordtest"
+)
+
+// quickRetries retries transient failures without slowing the tests down
+var quickRetries = discord.RetryPolicy{
+	MaxAttempts:   3,
+	BaseDelay:     time.Millisecond,
+	MaxDelay:      5 * time.Millisecond,
+	RetryStatuses: discord.DefaultRetryPolicy.RetryStatuses,
+}
+
+func newGuildServer(t *testing.T) *discordtest.Server {
+	t.Helper()
+	s := discordtest.NewServer()
+	t.Cleanup(s.Close)
+	s.Guilds = []discord.Guild{{ID: "10", Name: "Guild"}}
+	return s
+}
+
+func TestRateLimitIsRetried(t *testing.T) {
+	for _, global := range []bool{false, true} {
+		s := newGuildServer(t)
+		s.RateLimitNext(2, 20*time.Millisecond, global)
+
+		// Rate limits are waited out even when transient failures aren't retried
+		dc := s.Client(discord.WithRetryPolicy(discord.NoRetries))
+		start := time.Now()
+		guilds, err := dc.GetUserGuilds(t.Context())
+		if err != nil {
+			t.Fatalf("global %v: %v", global, err)
+		}
+		if len(guilds) != 1 {
+			t.Errorf("global %v: got %d guilds, want 1", global, len(guilds))
+		}
+		if n := s.RequestCount(); n != 3 {
+			t.Errorf("global %v: made %d requests, want 3", global, n)
+		}
+		if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+			t.Errorf("global %v: finished after %s, before both Retry-After delays", global, elapsed)
+		}
+	}
+}
+
+func TestRateLimitGivesUp(t *testing.T) {
+	s := newGuildServer(t)
+	s.RateLimitNext(100, time.Millisecond, false)
+
+	_, err := s.Client().GetUserGuilds(t.Context())
+	if err == nil || !strings.Contains(err.Error(), "429") {
+		t.Fatalf("err = %v, want a 429 error", err)
+	}
+	if n := s.RequestCount(); n != 6 {
+		t.Errorf("made %d requests, want the first and 5 retries", n)
+	}
+}
+
+func TestServerErrorsAreRetried(t *testing.T) {
+	s := newGuildServer(t)
+	s.FailNext(2, http.StatusBadGateway)
+
+	if _, err := s.Client(discord.WithRetryPolicy(quickRetries)).GetUserGuilds(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.RequestCount(); n != 3 {
+		t.Errorf("made %d requests, want 3", n)
+	}
+}
+
+func TestServerErrorsExhaustRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   discord.RetryPolicy
+		failures int
+		want     int
+	}{
+		{"retries", quickRetries, 5, 3},
+		{"no retries", discord.NoRetries, 1, 1},
+	}
+	for _, tt := range tests {
+		s := newGuildServer(t)
+		s.FailNext(tt.failures, http.StatusServiceUnavailable)
+
+		_, err := s.Client(discord.WithRetryPolicy(tt.policy)).GetUserGuilds(t.Context())
+		var apiErr *discord.APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("%s: err = %v, want a 503 APIError", tt.name, err)
+		}
+		if n := s.RequestCount(); n != tt.want {
+			t.Errorf("%s: made %d requests, want %d", tt.name, n, tt.want)
+		}
+	}
+}
+
+func TestPathErrors(t *testing.T) {
+	s := newGuildServer(t)
+	s.FailPath("/guilds/10/channels", http.StatusForbidden, 50001, "Missing Access")
+
+	_, err := s.Client().GetGuildChannels(t.Context(), "10")
+	if err == nil || !strings.Contains(err.Error(), "Missing Access") {
+		t.Errorf("err = %v, want Missing Access", err)
+	}
+	if n := s.RequestCount(); n != 1 {
+		t.Errorf("made %d requests, want 1", n)
+	}
+}
+
+func TestUnauthorized(t *testing.T) {
+	s := newGuildServer(t)
+	s.Token = "secret"
+
+	if _, err := s.Client().GetUserGuilds(t.Context()); err != nil {
+		t.Errorf("with the server's token: %v", err)
+	}
+	dc := discord.NewDiscordClient("wrong", false, discord.WithBaseURL(s.BaseURL()))
+	if _, err := dc.GetUserGuilds(t.Context()); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("with another token: err = %v, want a 401 error", err)
+	}
+}
+
+func TestCompressedResponses(t *testing.T) {
+	for _, encoding := range []string{"gzip", "deflate"} {
+		s := newGuildServer(t)
+		s.Encoding = encoding
+
+		guilds, err := s.Client().GetUserGuilds(t.Context())
+		if err != nil {
+			t.Fatalf("%s: %v", encoding, err)
+		}
+		if len(guilds) != 1 || guilds[0].Name != "Guild" {
+			t.Errorf("%s: guilds = %+v", encoding, guilds)
+		}
+	}
+}
+
+func TestGetMessagesPages(t *testing.T) {
+	s := discordtest.NewServer()
+	defer s.Close()
+
+	// Messages 1000 to 1149, a page and a half
+	alice := discordtest.NewUser("1", "alice", "Alice")
+	s.AddMessages("100", discordtest.GenerateMessages("100", alice, 1000, 150)...)
+
+	dc := s.Client()
+	first, err := dc.GetMessages(t.Context(), "100", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first) != 100 || first[0].ID != "1149" || first[99].ID != "1050" {
+		t.Fatalf("first page has %d messages, want 1149 to 1050", len(first))
+	}
+	second, err := dc.GetMessages(t.Context(), "100", first[99].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(second) != 50 || second[0].ID != "1049" || second[49].ID != "1000" {
+		t.Errorf("second page has %d messages, want 1049 to 1000", len(second))
+	}
+	if second[0].Author.Username != "alice" || second[0].Content != "message 50" {
+		t.Errorf("message 1049 = %+v", second[0])
+	}
+
+	if _, err := dc.GetMessages(t.Context(), "999", ""); err == nil || !strings.Contains(err.Error(), "Unknown Channel") {
+		t.Errorf("unknown channel: err = %v, want Unknown Channel", err)
+	}
+}
@@ -56,7 +56,14 @@ func buildSuperProperties(userAgent string) (string, error) {
 // buildRequest creates a new HTTP request with the necessary headers
 // and returns it. This is used internally to avoid code duplication.
 func (dc *DiscordClient) buildRequest(ctx context.Context, method, path string, queries url.Values, body io.ReadCloser) (*http.Request, error) {
-	u := &url.URL{Scheme: "https", Host: "discord.com", Path: fmt.Sprintf("/api/%s%s", ApiVersion, path)}
+	u, err := url.Parse(strings.TrimRight(dc.baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("parsing base URL: %w", err)
+	}
+	if dc.apiVersion != "" {
+		u.Path += "/" + dc.apiVersion
+	}
+	u.Path += path
 	if queries != nil {
 		u.RawQuery = queries.Encode()
 	}
@@ -0,0 +1,150 @@
+package discord
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Discord JSON error codes
+const (
+	ErrCodeGeneral            = 0
+	ErrCodeUnknownAccount     = 10001
+	ErrCodeUnknownChannel     = 10003
+	ErrCodeUnknownGuild       = 10004
+	ErrCodeUnknownMessage     = 10008
+	ErrCodeUnknownUser        = 10013
+	ErrCodeUnknownEmoji       = 10014
+	ErrCodeUnauthorized       = 40001
+	ErrCodeMissingAccess      = 50001
+	ErrCodeInvalidAccountType = 50002
+	ErrCodeMissingPermissions = 50013
+	ErrCodeInvalidFormBody    = 50035
+)
+
+// APIError is an error response returned by the Discord API.
+type APIError struct {
+	StatusCode int    // HTTP status code
+	Status     string // HTTP status line, e.g. "404 Not Found"
+	Code       int    `json:"code"`
+	Message    string `json:"message"`
+
+	// Errors holds the nested per-field errors of invalid form bodies
+	Errors json.RawMessage `json:"errors,omitempty"`
+
+	// Body is the raw (possibly truncated) response body
+	Body string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("request failed: %s: %s", e.Status, e.Body)
+	}
+
+	msg := fmt.Sprintf("request failed: %s: %s (code %d)", e.Status, e.Message, e.Code)
+	if fields := e.FieldErrors(); len(fields) > 0 {
+		keys := make([]string, 0, len(fields))
+		for k := range fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		parts := make([]string, 0, len(keys))
+		for _, k := range keys {
+			parts = append(parts, fmt.Sprintf("%s: %s", k, strings.Join(fields[k], "; ")))
+		}
+		msg += ": " + strings.Join(parts, ", ")
+	}
+	return msg
+}
+
+// FieldErrors flattens the nested errors object into a map of dotted field path to messages,
+// e.g. {"content": ["Must be 2000 or fewer in length."]}.
+func (e *APIError) FieldErrors() map[string][]string {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+
+	var tree map[string]any
+	if err := json.Unmarshal(e.Errors, &tree); err != nil {
+		return nil
+	}
+
+	out := make(map[string][]string)
+	flattenFieldErrors("", tree, out)
+	return out
+}
+
+func flattenFieldErrors(prefix string, node map[string]any, out map[string][]string) {
+	for key, value := range node {
+		// "_errors" lists are read from their parent below
+		child, ok := value.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+
+		if errs, ok := child["_errors"].([]any); ok {
+			for _, e := range errs {
+				if m, ok := e.(map[string]any); ok {
+					if s, ok := m["message"].(string); ok {
+						out[path] = append(out[path], s)
+					}
+				}
+			}
+		}
+		flattenFieldErrors(path, child, out)
+	}
+}
+
+// newAPIError builds an APIError from a non-2xx response, reading a limited amount of its body.
+func newAPIError(resp *http.Response) *APIError {
+	apiErr := &APIError{StatusCode: resp.StatusCode, Status: resp.Status}
+
+	r, err := decodeBody(resp)
+	if err != nil {
+		r = resp.Body
+	}
+	b, _ := io.ReadAll(io.LimitReader(r, 8192))
+	apiErr.Body = strings.TrimSpace(string(b))
+
+	// Discord error bodies are JSON, but proxies may return anything
+	_ = json.Unmarshal(b, apiErr)
+	return apiErr
+}
+
+// hasAPIError reports whether err wraps an APIError matching any of the given status codes or Discord codes.
+func hasAPIError(err error, statuses []int, codes []int) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return slices.Contains(statuses, apiErr.StatusCode) || (apiErr.Code != ErrCodeGeneral && slices.Contains(codes, apiErr.Code))
+}
+
+// IsNotFound reports whether err is a 404 or an unknown resource error.
+func IsNotFound(err error) bool {
+	return hasAPIError(err, []int{http.StatusNotFound}, []int{
+		ErrCodeUnknownAccount, ErrCodeUnknownChannel, ErrCodeUnknownGuild,
+		ErrCodeUnknownMessage, ErrCodeUnknownUser, ErrCodeUnknownEmoji,
+	})
+}
+
+// IsMissingAccess reports whether err is a 403 or a missing access / permissions error.
+func IsMissingAccess(err error) bool {
+	return hasAPIError(err, []int{http.StatusForbidden}, []int{ErrCodeMissingAccess, ErrCodeMissingPermissions})
+}
+
+// IsUnauthorized reports whether err is a 401, i.e. the token is invalid.
+func IsUnauthorized(err error) bool {
+	return hasAPIError(err, []int{http.StatusUnauthorized}, []int{ErrCodeUnauthorized})
+}
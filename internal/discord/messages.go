@@ -0,0 +1,154 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"slices"
+	"strconv"
+
+	"github.com/CaptainFallaway/Discorder/internal/snowflake"
+)
+
+// MessagePage selects a single page of channel messages.
+// Only one of Before, After and Around is used, in that order of precedence.
+type MessagePage struct {
+	Before string // Messages older than this ID
+	After  string // Messages newer than this ID
+	Around string // Messages around this ID
+	Limit  int    // Page size, defaults to MessageLimit
+}
+
+// IterMessagesOptions selects a range of messages and the direction IterMessages walks it in.
+type IterMessagesOptions struct {
+	// Before only includes messages older than this ID
+	Before string
+	// After only includes messages newer than this ID
+	After string
+	// Around fetches a single page of messages centred on this ID, ignoring Before and After
+	Around string
+	// OldestFirst walks forward in time. Without After it starts at the channel's first message.
+	OldestFirst bool
+	// Limit stops after this many messages, 0 means no limit
+	Limit int
+}
+
+// IterMessages returns an iterator over a channel's messages, fetching pages lazily as it is consumed.
+// Messages are yielded newest first unless OldestFirst is set.
+// Iteration stops after the first error, which is yielded with a zero Message.
+func (dc *DiscordClient) IterMessages(ctx context.Context, channelID string, opts IterMessagesOptions) iter.Seq2[Message, error] {
+	return func(yield func(Message, error) bool) {
+		pageSize, _ := strconv.Atoi(MessageLimit)
+
+		if opts.Around != "" {
+			limit := pageSize
+			if opts.Limit > 0 {
+				limit = min(pageSize, opts.Limit)
+			}
+			messages, err := dc.GetMessagesPage(ctx, channelID, MessagePage{Around: opts.Around, Limit: limit})
+			if err != nil {
+				yield(Message{}, err)
+				return
+			}
+			if opts.OldestFirst {
+				slices.Reverse(messages)
+			}
+			for _, m := range messages {
+				if !yield(m, nil) {
+					return
+				}
+			}
+			return
+		}
+
+		var page MessagePage
+		if opts.OldestFirst {
+			page.After = opts.After
+			if page.After == "" {
+				page.After = "0"
+			}
+		} else {
+			page.Before = opts.Before
+		}
+
+		// The far bound of the walk is parsed once, so messages are compared to it numerically
+		far := opts.After
+		if opts.OldestFirst {
+			far = opts.Before
+		}
+		var bound snowflake.Snowflake
+		if far != "" {
+			var err error
+			if bound, err = snowflake.Parse(far); err != nil {
+				yield(Message{}, fmt.Errorf("invalid message bound: %w", err))
+				return
+			}
+		}
+
+		// inRange reports whether a message is within the far bound of the walk
+		inRange := func(m Message) bool {
+			if far == "" {
+				return true
+			}
+			id, err := snowflake.Parse(m.ID)
+			if err != nil {
+				return false
+			}
+			if opts.OldestFirst {
+				return id < bound
+			}
+			return id > bound
+		}
+
+		yielded := 0
+		for {
+			if opts.Limit > 0 {
+				page.Limit = min(pageSize, opts.Limit-yielded)
+			}
+
+			messages, err := dc.GetMessagesPage(ctx, channelID, page)
+			if err != nil {
+				yield(Message{}, err)
+				return
+			}
+
+			if len(messages) == 0 {
+				return
+			}
+
+			// Pages are always returned newest first
+			if opts.OldestFirst {
+				slices.Reverse(messages)
+			}
+
+			for _, m := range messages {
+				if !inRange(m) {
+					return
+				}
+				if !yield(m, nil) {
+					return
+				}
+				yielded++
+				if opts.Limit > 0 && yielded >= opts.Limit {
+					return
+				}
+			}
+
+			last := messages[len(messages)-1].ID
+			if opts.OldestFirst {
+				page.After = last
+			} else {
+				page.Before = last
+			}
+
+			// A short page means there is nothing more to fetch
+			requested := pageSize
+			if page.Limit > 0 {
+				requested = page.Limit
+			}
+			if len(messages) < requested {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package discord_test
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/CaptainFallaway/Discorder/internal/discord"
+	"github.com/CaptainFallaway/Discorder/internal/discordtest"
+)
+
+// idRange returns the IDs from first to last inclusive, counting down if last is smaller
+func idRange(first, last int) []string {
+	step := 1
+	if last < first {
+		step = -1
+	}
+	var ids []string
+	for id := first; ; id += step {
+		ids = append(ids, strconv.Itoa(id))
+		if id == last {
+			return ids
+		}
+	}
+}
+
+func TestIterMessages(t *testing.T) {
+	s := discordtest.NewServer()
+	defer s.Close()
+
+	// Messages 1000 to 1249, two and a half pages
+	alice := discordtest.NewUser("1", "alice", "Alice")
+	s.AddMessages("100", discordtest.GenerateMessages("100", alice, 1000, 250)...)
+
+	tests := []struct {
+		name     string
+		opts     discord.IterMessagesOptions
+		want     []string
+		requests int
+	}{
+		{"newest first", discord.IterMessagesOptions{}, idRange(1249, 1000), 3},
+		{"before", discord.IterMessagesOptions{Before: "1100"}, idRange(1099, 1000), 2},
+		{"after", discord.IterMessagesOptions{After: "1200"}, idRange(1249, 1201), 1},
+		{"after a shorter ID", discord.IterMessagesOptions{After: "999"}, idRange(1249, 1000), 3},
+		{"before and after", discord.IterMessagesOptions{Before: "1100", After: "1050"}, idRange(1099, 1051), 1},
+		{"limit", discord.IterMessagesOptions{Limit: 5}, idRange(1249, 1245), 1},
+		{"oldest first", discord.IterMessagesOptions{OldestFirst: true}, idRange(1000, 1249), 3},
+		{"oldest first after", discord.IterMessagesOptions{OldestFirst: true, After: "1200"}, idRange(1201, 1249), 1},
+		{"oldest first before", discord.IterMessagesOptions{OldestFirst: true, Before: "1010"}, idRange(1000, 1009), 1},
+		{"oldest first limit", discord.IterMessagesOptions{OldestFirst: true, Limit: 150}, idRange(1000, 1149), 2},
+		{"around", discord.IterMessagesOptions{Around: "1100", Limit: 10}, idRange(1105, 1096), 1},
+		{"around oldest first", discord.IterMessagesOptions{Around: "1100", Limit: 10, OldestFirst: true}, idRange(1096, 1105), 1},
+	}
+
+	dc := s.Client(discord.WithRetryPolicy(discord.NoRetries))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := s.RequestCount()
+			var got []string
+			for m, err := range dc.IterMessages(t.Context(), "100", tt.opts) {
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, m.ID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %d messages %v...\nwant %d messages %v...", len(got), got[:min(5, len(got))], len(tt.want), tt.want[:5])
+			}
+			if n := s.RequestCount() - before; n != tt.requests {
+				t.Errorf("made %d requests, want %d", n, tt.requests)
+			}
+		})
+	}
+}
+
+func TestIterMessagesStopsAtError(t *testing.T) {
+	s := discordtest.NewServer()
+	defer s.Close()
+	s.FailPath("/channels/100/messages", 403, discord.ErrCodeMissingAccess, "Missing Access")
+
+	var messages, errs int
+	for _, err := range s.Client().IterMessages(t.Context(), "100", discord.IterMessagesOptions{}) {
+		if err != nil {
+			errs++
+			continue
+		}
+		messages++
+	}
+	if messages != 0 || errs != 1 {
+		t.Errorf("yielded %d messages and %d errors, want only the error", messages, errs)
+	}
+}
+
+func TestIterMessagesInvalidBound(t *testing.T) {
+	s := discordtest.NewServer()
+	defer s.Close()
+
+	var errs int
+	for _, err := range s.Client().IterMessages(t.Context(), "100", discord.IterMessagesOptions{After: "yesterday"}) {
+		if err == nil {
+			t.Fatal("yielded a message, want only an error")
+		}
+		errs++
+	}
+	if errs != 1 || s.RequestCount() != 0 {
+		t.Errorf("yielded %d errors after %d requests, want one error before any request", errs, s.RequestCount())
+	}
+}
@@ -0,0 +1,67 @@
+package discord
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// DefaultBaseURL is the root of the Discord API, without the version segment
+const DefaultBaseURL = "https://discord.com/api"
+
+// Logger receives debug output from the client.
+// *log.Logger satisfies this interface.
+type Logger interface {
+	Printf(format string, args ...any)
+}
+
+// stdoutLogger is the default debug logger, printing to standard output.
+type stdoutLogger struct{}
+
+func (stdoutLogger) Printf(format string, args ...any) {
+	fmt.Printf(format+"\n", args...)
+}
+
+// Option configures a DiscordClient.
+type Option func(*DiscordClient)
+
+// WithBaseURL sets the API root the client talks to, e.g. a local stand-in server.
+// The API version is appended to it.
+func WithBaseURL(baseURL string) Option {
+	return func(dc *DiscordClient) {
+		dc.baseURL = baseURL
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to make requests.
+func WithHTTPClient(client *http.Client) Option {
+	return func(dc *DiscordClient) {
+		if client != nil {
+			dc.client = client
+		}
+	}
+}
+
+// WithAPIVersion sets the API version segment, e.g. "v10".
+func WithAPIVersion(version string) Option {
+	return func(dc *DiscordClient) {
+		dc.apiVersion = version
+	}
+}
+
+// WithTimeout sets the timeout of the client's HTTP client.
+func WithTimeout(timeout time.Duration) Option {
+	return func(dc *DiscordClient) {
+		dc.timeout = timeout
+	}
+}
+
+// WithLogger sets the logger used for debug output and enables debug output.
+func WithLogger(logger Logger) Option {
+	return func(dc *DiscordClient) {
+		if logger != nil {
+			dc.logger = logger
+			dc.debug = true
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rate limit headers returned by Discord
+const (
+	headerRetryAfter          = "Retry-After"
+	headerRateLimitRemaining  = "X-RateLimit-Remaining"
+	headerRateLimitResetAfter = "X-RateLimit-Reset-After"
+	headerRateLimitBucket     = "X-RateLimit-Bucket"
+	headerRateLimitGlobal     = "X-RateLimit-Global"
+	headerRateLimitScope      = "X-RateLimit-Scope"
+)
+
+// maxRateLimitRetries caps how many 429 responses are retried for a single request
+const maxRateLimitRetries = 5
+
+// bucket holds the known state of a single rate limit bucket.
+type bucket struct {
+	mu        sync.Mutex
+	remaining int
+	resetAt   time.Time
+	known     bool
+}
+
+// rateLimiter tracks Discord rate limit buckets per route and the global limit.
+//
+// Discord does not tell us a route's bucket until it has been requested once,
+// so routes are first keyed by themselves and later mapped to the bucket hash
+// from the X-RateLimit-Bucket header. Buckets are further split by the major
+// parameter (channel or guild ID) in the route.
+type rateLimiter struct {
+	mu          sync.Mutex
+	routes      map[string]string  // route -> bucket hash
+	buckets     map[string]*bucket // bucket hash + major parameter -> bucket
+	globalReset time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		routes:  make(map[string]string),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// routeKey returns the rate limit route for a request, with minor parameters
+// (IDs that are not channel or guild IDs, and reaction emoji) replaced by a placeholder.
+func routeKey(method, path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for i := 1; i < len(parts); i++ {
+		// Every emoji of a message shares the reactions limit
+		if parts[i-1] == "reactions" {
+			parts[i] = "{emoji}"
+			continue
+		}
+		if !isNumeric(parts[i]) {
+			continue
+		}
+		if parts[i-1] == "channels" || parts[i-1] == "guilds" || parts[i-1] == "webhooks" {
+			continue
+		}
+		parts[i] = "{id}"
+	}
+	return method + " /" + strings.Join(parts, "/")
+}
+
+// majorParameter returns the channel, guild or webhook ID a route is scoped to, if any.
+func majorParameter(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) >= 2 {
+		switch parts[0] {
+		case "channels", "guilds", "webhooks":
+			return parts[1]
+		}
+	}
+	return ""
+}
+
+func isNumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// getBucket returns the bucket for a route, creating it if necessary.
+func (rl *rateLimiter) getBucket(method, path string) *bucket {
+	route := routeKey(method, path)
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	key := route
+	if hash, ok := rl.routes[route]; ok {
+		key = hash + ":" + majorParameter(path)
+	}
+
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &bucket{}
+		rl.buckets[key] = b
+	}
+	return b
+}
+
+// wait blocks until a request on the given route is allowed to be sent, or until ctx is done.
+// It returns how long it waited.
+func (rl *rateLimiter) wait(ctx context.Context, method, path string) (time.Duration, error) {
+	var waited time.Duration
+
+	rl.mu.Lock()
+	globalReset := rl.globalReset
+	rl.mu.Unlock()
+
+	if d := time.Until(globalReset); d > 0 {
+		if err := sleepContext(ctx, d); err != nil {
+			return waited, err
+		}
+		waited += d
+	}
+
+	b := rl.getBucket(method, path)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.known && b.remaining <= 0 {
+		if d := time.Until(b.resetAt); d > 0 {
+			if err := sleepContext(ctx, d); err != nil {
+				return waited, err
+			}
+			waited += d
+		}
+		b.known = false
+	}
+
+	// Optimistically consume a request so concurrent callers don't overrun the bucket
+	if b.known {
+		b.remaining--
+	}
+
+	return waited, nil
+}
+
+// update records the rate limit headers of a response for the given route.
+func (rl *rateLimiter) update(method, path string, header http.Header) {
+	route := routeKey(method, path)
+
+	if hash := header.Get(headerRateLimitBucket); hash != "" {
+		rl.mu.Lock()
+		if old, ok := rl.routes[route]; !ok || old != hash {
+			rl.routes[route] = hash
+			key := hash + ":" + majorParameter(path)
+			if _, exists := rl.buckets[key]; !exists {
+				rl.buckets[key] = &bucket{}
+			}
+		}
+		rl.mu.Unlock()
+	}
+
+	remaining, errRemaining := strconv.Atoi(header.Get(headerRateLimitRemaining))
+	resetAfter, errReset := strconv.ParseFloat(header.Get(headerRateLimitResetAfter), 64)
+	if errRemaining != nil || errReset != nil {
+		return
+	}
+
+	b := rl.getBucket(method, path)
+	b.mu.Lock()
+	b.remaining = remaining
+	b.resetAt = time.Now().Add(secondsToDuration(resetAfter))
+	b.known = true
+	b.mu.Unlock()
+}
+
+// rateLimitResponse is the JSON body Discord sends with a 429 response.
+type rateLimitResponse struct {
+	Message    string  `json:"message"`
+	RetryAfter float64 `json:"retry_after"`
+	Global     bool    `json:"global"`
+}
+
+// handleTooManyRequests reads a 429 response and returns how long to wait before retrying.
+// Global rate limits also pause every other route until they expire.
+func (rl *rateLimiter) handleTooManyRequests(resp *http.Response) time.Duration {
+	var body rateLimitResponse
+	r, err := decodeBody(resp)
+	if err != nil {
+		r = resp.Body
+	}
+	b, _ := io.ReadAll(io.LimitReader(r, 8192))
+	_ = json.Unmarshal(b, &body)
+
+	retryAfter := secondsToDuration(body.RetryAfter)
+	if v, err := strconv.ParseFloat(resp.Header.Get(headerRetryAfter), 64); err == nil && retryAfter == 0 {
+		retryAfter = secondsToDuration(v)
+	}
+	if retryAfter <= 0 {
+		retryAfter = time.Second
+	}
+
+	global := body.Global || resp.Header.Get(headerRateLimitGlobal) == "true" || resp.Header.Get(headerRateLimitScope) == "global"
+	if global {
+		rl.mu.Lock()
+		rl.globalReset = time.Now().Add(retryAfter)
+		rl.mu.Unlock()
+	}
+
+	return retryAfter
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// sleepContext sleeps for d or until ctx is done, whichever comes first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,23 @@
+package discord
+
+import "testing"
+
+func TestRouteKey(t *testing.T) {
+	tests := []struct {
+		method, path, want string
+	}{
+		{"GET", "/users/@me/guilds", "GET /users/@me/guilds"},
+		{"GET", "/channels/100/messages", "GET /channels/100/messages"},
+		{"GET", "/channels/100/messages/1001", "GET /channels/100/messages/{id}"},
+		{"GET", "/guilds/10/channels", "GET /guilds/10/channels"},
+		{"DELETE", "/channels/100", "DELETE /channels/100"},
+		{"GET", "/channels/100/messages/1001/reactions/%F0%9F%91%8D", "GET /channels/100/messages/{id}/reactions/{emoji}"},
+		{"GET", "/channels/100/messages/1002/reactions/blob:500", "GET /channels/100/messages/{id}/reactions/{emoji}"},
+		{"PUT", "/channels/100/messages/1001/reactions/%F0%9F%91%8D/@me", "PUT /channels/100/messages/{id}/reactions/{emoji}/@me"},
+	}
+	for _, tt := range tests {
+		if got := routeKey(tt.method, tt.path); got != tt.want {
+			t.Errorf("routeKey(%s, %s) = %q, want %q", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"net/url"
+	"strconv"
+)
+
+// Reaction types of the reactions endpoint
+const (
+	ReactionTypeNormal = 0
+	ReactionTypeBurst  = 1
+)
+
+// ReactionLimit is the page size of reaction user listings, the API's maximum
+const ReactionLimit = 100
+
+// ReactionsPage selects a single page of the users who reacted with an emoji.
+type ReactionsPage struct {
+	// After only includes users with an ID greater than this
+	After string
+	// Limit is the page size, defaults to ReactionLimit
+	Limit int
+	// Type is ReactionTypeNormal or ReactionTypeBurst
+	Type int
+}
+
+// GetReactionsPage retrieves a single page of the users who reacted to a message with an emoji, ordered by ID
+func (dc *DiscordClient) GetReactionsPage(ctx context.Context, channelID, messageID string, emoji Emoji, page ReactionsPage) ([]User, error) {
+	path := fmt.Sprintf("/channels/%s/messages/%s/reactions/%s", channelID, messageID, emoji.APIName())
+
+	queries := url.Values{
+		"limit": []string{strconv.Itoa(ReactionLimit)},
+		"type":  []string{strconv.Itoa(page.Type)},
+	}
+	if page.Limit > 0 {
+		queries.Set("limit", strconv.Itoa(page.Limit))
+	}
+	if page.After != "" {
+		queries.Set("after", page.After)
+	}
+
+	body, err := dc.RequestWithOptions(ctx, "GET", path, queries, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching reactions: %w", err)
+	}
+	defer body.Close()
+
+	users := make([]User, 0)
+	if err := json.NewDecoder(body).Decode(&users); err != nil {
+		return nil, fmt.Errorf("error parsing JSON response: %w", err)
+	}
+	return users, nil
+}
+
+// IterReactions returns an iterator over the users who reacted to a message with an emoji,
+// normally or with burst reactions, fetching pages lazily as it is consumed.
+// Iteration stops after the first error, which is yielded with a zero User.
+func (dc *DiscordClient) IterReactions(ctx context.Context, channelID, messageID string, emoji Emoji, reactionType int) iter.Seq2[User, error] {
+	return func(yield func(User, error) bool) {
+		page := ReactionsPage{Type: reactionType}
+		for {
+			users, err := dc.GetReactionsPage(ctx, channelID, messageID, emoji, page)
+			if err != nil {
+				yield(User{}, err)
+				return
+			}
+
+			for _, u := range users {
+				if !yield(u, nil) {
+					return
+				}
+			}
+
+			if len(users) < ReactionLimit {
+				return
+			}
+			page.After = users[len(users)-1].ID
+		}
+	}
+}
+
+// GetReactionUsers retrieves every user who reacted to a message with an emoji
+func (dc *DiscordClient) GetReactionUsers(ctx context.Context, channelID, messageID string, emoji Emoji, reactionType int) ([]User, error) {
+	var users []User
+	for u, err := range dc.IterReactions(ctx, channelID, messageID, emoji, reactionType) {
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, nil
+}
@@ -0,0 +1,97 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// RetryPolicy controls how transient failures (network errors and 5xx responses) are retried.
+// Rate limited (429) responses are handled separately and always retried.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first. 1 disables retries.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, doubled on each following retry
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between retries
+	MaxDelay time.Duration
+	// RetryStatuses are the HTTP statuses considered transient
+	RetryStatuses []int
+	// RetryNonIdempotent allows retrying methods such as POST that may have side effects
+	RetryNonIdempotent bool
+}
+
+// DefaultRetryPolicy retries idempotent requests up to 5 times over roughly 15 seconds.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    8 * time.Second,
+	RetryStatuses: []int{
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	},
+}
+
+// NoRetries disables retries of transient failures.
+var NoRetries = RetryPolicy{MaxAttempts: 1}
+
+// WithRetryPolicy sets the policy for retrying transient failures.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(dc *DiscordClient) {
+		dc.retry = policy
+	}
+}
+
+// canRetry reports whether another attempt may be made after the given number of attempts.
+func (p RetryPolicy) canRetry(method string, attempts int) bool {
+	if attempts >= p.MaxAttempts {
+		return false
+	}
+	return p.RetryNonIdempotent || isIdempotent(method)
+}
+
+// retryableStatus reports whether a response status is considered transient.
+func (p RetryPolicy) retryableStatus(status int) bool {
+	return slices.Contains(p.RetryStatuses, status)
+}
+
+// retryableError reports whether an error from the HTTP client is worth retrying.
+// Cancellations and deadlines of the caller's context are not, but per-request timeouts
+// of the HTTP client are, although they also match context.DeadlineExceeded.
+func retryableError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	return !errors.Is(err, context.Canceled)
+}
+
+// backoff returns the jittered delay before the given retry (1 for the first retry).
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < retry && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	// Equal jitter: between half and the whole delay
+	return d/2 + rand.N(d/2+1)
+}
+
+// isIdempotent reports whether repeating a request with this method is safe.
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,75 @@
+package discord
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var fastRetries = RetryPolicy{
+	MaxAttempts:   3,
+	BaseDelay:     time.Millisecond,
+	MaxDelay:      5 * time.Millisecond,
+	RetryStatuses: DefaultRetryPolicy.RetryStatuses,
+}
+
+func TestRequestTimeoutIsRetried(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			// Outlast the client's timeout once
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+			}
+			return
+		}
+		w.Write([]byte(`{"id":"1","name":"general"}`))
+	}))
+	defer srv.Close()
+
+	dc := NewDiscordClient("token", false, WithBaseURL(srv.URL), WithTimeout(50*time.Millisecond), WithRetryPolicy(fastRetries))
+	channel, err := dc.GetChannel(context.Background(), "1")
+	if err != nil {
+		t.Fatalf("GetChannel: %v", err)
+	}
+	if channel.Name != "general" {
+		t.Errorf("channel name = %q, want general", channel.Name)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("made %d requests, want 2", n)
+	}
+}
+
+func TestCancelledRequestIsNotRetried(t *testing.T) {
+	var requests atomic.Int32
+	ctx, cancel := context.WithCancel(context.Background())
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		cancel()
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	dc := NewDiscordClient("token", false, WithBaseURL(srv.URL), WithRetryPolicy(fastRetries))
+	if _, err := dc.GetChannel(ctx, "1"); err == nil {
+		t.Fatal("GetChannel succeeded after its context was cancelled")
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("made %d requests, want 1", n)
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for retry, full := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 5: time.Second} {
+		for range 100 {
+			if d := p.backoff(retry); d < full/2 || d > full {
+				t.Fatalf("backoff(%d) = %s, want between %s and %s", retry, d, full/2, full)
+			}
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package discord
+
+import (
+	"cmp"
+	"strings"
+	"time"
+
+	"github.com/CaptainFallaway/Discorder/internal/snowflake"
+)
+
+// SnowflakeFromTime returns the smallest snowflake ID created at t, for use in before/after queries.
+func SnowflakeFromTime(t time.Time) string {
+	return snowflake.FromTime(t).String()
+}
+
+// createdAt returns the creation time encoded in an ID, or the zero time if it isn't a snowflake
+func createdAt(id string) time.Time {
+	s, err := snowflake.Parse(id)
+	if err != nil {
+		return time.Time{}
+	}
+	return s.Time()
+}
+
+// CompareIDs compares two snowflake IDs numerically, returning -1, 0 or 1.
+// IDs that aren't snowflakes, such as empty ones, sort before every snowflake.
+func CompareIDs(a, b string) int {
+	sa, errA := snowflake.Parse(a)
+	sb, errB := snowflake.Parse(b)
+	switch {
+	case errA != nil && errB != nil:
+		return strings.Compare(a, b)
+	case errA != nil:
+		return -1
+	case errB != nil:
+		return 1
+	}
+	return cmp.Compare(sa, sb)
+}
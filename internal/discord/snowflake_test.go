@@ -0,0 +1,32 @@
+package discord
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCompareIDs(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"9", "10", -1},
+		{"175928847299117063", "99999999999999999", 1},
+		{"1001", "1001", 0},
+		{"", "1", -1},
+		{"abc", "1", -1},
+		{"1", "", 1},
+		{"", "", 0},
+	}
+	for _, tt := range tests {
+		if got := CompareIDs(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareIDs(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+
+	ids := []string{"1000", "", "99", "175928847299117063", "100"}
+	slices.SortFunc(ids, CompareIDs)
+	if want := []string{"", "99", "100", "1000", "175928847299117063"}; !slices.Equal(ids, want) {
+		t.Errorf("sorted IDs = %q, want %q", ids, want)
+	}
+}
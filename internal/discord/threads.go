@@ -0,0 +1,173 @@
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"net/url"
+	"slices"
+	"strconv"
+)
+
+// ThreadLimit is the page size of archived thread listings, the API's maximum
+const ThreadLimit = 100
+
+// ThreadList is a page of threads as returned by the thread listing endpoints.
+type ThreadList struct {
+	Threads []Channel `json:"threads"`
+	HasMore bool      `json:"has_more"`
+}
+
+// ArchivedThreadsPage selects a single page of archived threads.
+type ArchivedThreadsPage struct {
+	// Private lists private rather than public threads, which requires the Manage Threads permission
+	Private bool
+	// Before only includes threads archived before this ISO8601 timestamp
+	Before string
+	// Limit is the page size, defaults to ThreadLimit
+	Limit int
+}
+
+// GetGuildActiveThreads retrieves the active (unarchived) threads of every channel in a guild.
+// Their ParentID tells which channel each belongs to.
+func (dc *DiscordClient) GetGuildActiveThreads(ctx context.Context, guildID string) ([]Channel, error) {
+	path := fmt.Sprintf("/guilds/%s/threads/active", guildID)
+	body, err := dc.Request(ctx, "GET", path)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching active threads: %w", err)
+	}
+	defer body.Close()
+
+	var list ThreadList
+	if err := json.NewDecoder(body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("error parsing JSON response: %w", err)
+	}
+	return list.Threads, nil
+}
+
+// GetArchivedThreadsPage retrieves a single page of a channel's archived threads, most recently archived first
+func (dc *DiscordClient) GetArchivedThreadsPage(ctx context.Context, channelID string, page ArchivedThreadsPage) (ThreadList, error) {
+	visibility := "public"
+	if page.Private {
+		visibility = "private"
+	}
+	path := fmt.Sprintf("/channels/%s/threads/archived/%s", channelID, visibility)
+
+	queries := url.Values{
+		"limit": []string{strconv.Itoa(ThreadLimit)},
+	}
+	if page.Limit > 0 {
+		queries.Set("limit", strconv.Itoa(page.Limit))
+	}
+	if page.Before != "" {
+		queries.Set("before", page.Before)
+	}
+
+	body, err := dc.RequestWithOptions(ctx, "GET", path, queries, nil)
+	if err != nil {
+		return ThreadList{}, fmt.Errorf("error fetching archived threads: %w", err)
+	}
+	defer body.Close()
+
+	var list ThreadList
+	if err := json.NewDecoder(body).Decode(&list); err != nil {
+		return ThreadList{}, fmt.Errorf("error parsing JSON response: %w", err)
+	}
+	return list, nil
+}
+
+// IterArchivedThreads returns an iterator over a channel's public or private archived threads,
+// most recently archived first, fetching pages lazily as it is consumed.
+// Iteration stops after the first error, which is yielded with a zero Channel.
+func (dc *DiscordClient) IterArchivedThreads(ctx context.Context, channelID string, private bool) iter.Seq2[Channel, error] {
+	return func(yield func(Channel, error) bool) {
+		page := ArchivedThreadsPage{Private: private}
+		for {
+			list, err := dc.GetArchivedThreadsPage(ctx, channelID, page)
+			if err != nil {
+				yield(Channel{}, err)
+				return
+			}
+
+			for _, thread := range list.Threads {
+				if !yield(thread, nil) {
+					return
+				}
+			}
+
+			if !list.HasMore || len(list.Threads) == 0 {
+				return
+			}
+
+			// Threads are paginated by archive time rather than ID
+			last := list.Threads[len(list.Threads)-1]
+			if last.ThreadMetadata == nil || last.ThreadMetadata.ArchiveTimestamp == "" || last.ThreadMetadata.ArchiveTimestamp == page.Before {
+				return
+			}
+			page.Before = last.ThreadMetadata.ArchiveTimestamp
+		}
+	}
+}
+
+// GetArchivedThreads retrieves the public and, if permitted, private archived threads of a channel.
+func (dc *DiscordClient) GetArchivedThreads(ctx context.Context, channelID string) ([]Channel, error) {
+	var threads []Channel
+	for _, private := range []bool{false, true} {
+		for thread, err := range dc.IterArchivedThreads(ctx, channelID, private) {
+			if err != nil {
+				// Listing private threads needs Manage Threads, which most users don't have
+				if private && IsMissingAccess(err) {
+					break
+				}
+				return nil, err
+			}
+			threads = append(threads, thread)
+		}
+	}
+	return threads, nil
+}
+
+// GetChannelThreads retrieves every thread of a channel in a guild: active, public archived and,
+// if permitted, private archived threads. Threads are returned oldest first.
+// To list the threads of several channels, fetch the guild's active threads once with
+// GetGuildActiveThreads and merge them with each channel's GetArchivedThreads instead.
+func (dc *DiscordClient) GetChannelThreads(ctx context.Context, guildID, channelID string) ([]Channel, error) {
+	active, err := dc.GetGuildActiveThreads(ctx, guildID)
+	if err != nil {
+		return nil, err
+	}
+	archived, err := dc.GetArchivedThreads(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	return MergeThreads(ThreadsOf(active, channelID), archived), nil
+}
+
+// ThreadsOf returns the threads whose parent is channelID.
+func ThreadsOf(threads []Channel, channelID string) []Channel {
+	var children []Channel
+	for _, t := range threads {
+		if t.ParentID == channelID {
+			children = append(children, t)
+		}
+	}
+	return children
+}
+
+// MergeThreads combines thread lists, such as active and archived threads, dropping duplicates.
+// Threads are returned oldest first.
+func MergeThreads(lists ...[]Channel) []Channel {
+	var threads []Channel
+	seen := make(map[string]bool)
+	for _, list := range lists {
+		for _, t := range list {
+			if !seen[t.ID] {
+				seen[t.ID] = true
+				threads = append(threads, t)
+			}
+		}
+	}
+	slices.SortFunc(threads, func(a, b Channel) int { return CompareIDs(a.ID, b.ID) })
+	return threads
+}
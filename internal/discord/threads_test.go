@@ -0,0 +1,62 @@
+package discord_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/CaptainFallaway/Discorder/internal/discord"
+	"github.com/CaptainFallaway/Discorder/internal/discordtest"
+)
+
+func TestGetChannelThreads(t *testing.T) {
+	s := discordtest.NewServer()
+	defer s.Close()
+
+	s.GuildChannels["1"] = []discord.Channel{
+		{ID: "100", GuildID: "1", Name: "general"},
+		{ID: "200", GuildID: "1", Name: "other"},
+	}
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var threads []discord.Channel
+	// 130 archived threads, more than one page
+	for i := range 130 {
+		threads = append(threads, discordtest.NewThread(strconv.Itoa(1000+i), "100", "archived", start.Add(time.Duration(i)*time.Hour)))
+	}
+	threads = append(threads, discordtest.NewThread("5000", "100", "active", time.Time{}))
+	s.Threads["100"] = threads
+	s.Threads["200"] = []discord.Channel{discordtest.NewThread("6000", "200", "elsewhere", time.Time{})}
+
+	dc := s.Client(discord.WithRetryPolicy(discord.NoRetries))
+	got, err := dc.GetChannelThreads(context.Background(), "1", "100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 131 {
+		t.Fatalf("got %d threads, want 131", len(got))
+	}
+	for i := 1; i < len(got); i++ {
+		if discord.CompareIDs(got[i-1].ID, got[i].ID) >= 0 {
+			t.Fatalf("threads not sorted oldest first at %d: %s then %s", i, got[i-1].ID, got[i].ID)
+		}
+	}
+	if last := got[len(got)-1]; last.ID != "5000" {
+		t.Errorf("last thread = %s, want the active thread 5000", last.ID)
+	}
+
+	for _, r := range s.Requests {
+		if r.Path == "/channels/100/threads/active" {
+			t.Errorf("used the channel active threads endpoint, removed in API v10")
+		}
+	}
+}
+
+func TestMergeThreads(t *testing.T) {
+	a := []discord.Channel{{ID: "3"}, {ID: "1"}}
+	b := []discord.Channel{{ID: "2"}, {ID: "3"}}
+	got := discord.MergeThreads(a, b)
+	if len(got) != 3 || got[0].ID != "1" || got[1].ID != "2" || got[2].ID != "3" {
+		t.Errorf("MergeThreads = %v, want 1, 2, 3", got)
+	}
+}
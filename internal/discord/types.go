@@ -1,6 +1,14 @@
 package discord
 
-import "fmt"
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/CaptainFallaway/Discorder/internal/snowflake"
+)
 
 // Relationship types
 const (
@@ -37,6 +45,11 @@ type User struct {
 	Avatar     string `json:"avatar"`
 }
 
+// CreatedAt returns when the account was created, the zero time if the ID is invalid.
+func (u User) CreatedAt() time.Time {
+	return createdAt(u.ID)
+}
+
 // GetName returns the user's name, preferring global name if available.
 func (u User) GetName() string {
 	if u.GlobalName == "" {
@@ -45,6 +58,26 @@ func (u User) GetName() string {
 	return fmt.Sprintf("%s (%s)", u.GlobalName, u.Username)
 }
 
+// CDNURL is the root of Discord's content delivery network
+const CDNURL = "https://cdn.discordapp.com"
+
+// AvatarURL returns the URL of the user's avatar, or of their default avatar if they have none.
+func (u User) AvatarURL() string {
+	if u.Avatar == "" {
+		index := uint64(0)
+		if id, err := snowflake.Parse(u.ID); err == nil {
+			index = uint64(id>>22) % 6
+		}
+		return fmt.Sprintf("%s/embed/avatars/%d.png", CDNURL, index)
+	}
+
+	ext := "png"
+	if strings.HasPrefix(u.Avatar, "a_") {
+		ext = "gif"
+	}
+	return fmt.Sprintf("%s/avatars/%s/%s.%s?size=64", CDNURL, u.ID, u.Avatar, ext)
+}
+
 // Relationship is a partial relationship record.
 type Relationship struct {
 	ID       string `json:"id"`
@@ -58,9 +91,57 @@ type Relationship struct {
 type Channel struct {
 	ID         string `json:"id"`
 	Type       int    `json:"type"`
+	GuildID    string `json:"guild_id,omitempty"`
+	ParentID   string `json:"parent_id,omitempty"`
+	Position   int    `json:"position,omitempty"`
 	Name       string `json:"name"`
+	Topic      string `json:"topic,omitempty"`
 	Recipients []User `json:"recipients"`
 	NSFW       bool   `json:"nsfw"`
+
+	// Thread fields
+	OwnerID        string          `json:"owner_id,omitempty"`
+	MessageCount   int             `json:"message_count,omitempty"`
+	MemberCount    int             `json:"member_count,omitempty"`
+	ThreadMetadata *ThreadMetadata `json:"thread_metadata,omitempty"`
+
+	// Forum and media channel fields
+	AvailableTags []ForumTag `json:"available_tags,omitempty"`
+	AppliedTags   []string   `json:"applied_tags,omitempty"` // Tag IDs, on forum posts
+}
+
+// CreatedAt returns when the channel was created, the zero time if the ID is invalid.
+func (c Channel) CreatedAt() time.Time {
+	return createdAt(c.ID)
+}
+
+// IsThread reports whether the channel is a thread.
+func (c Channel) IsThread() bool {
+	return c.Type == ChannelAnnouncementThread || c.Type == ChannelPublicThread || c.Type == ChannelPrivateThread
+}
+
+// IsForum reports whether the channel is a forum or media channel, whose posts are threads.
+func (c Channel) IsForum() bool {
+	return c.Type == ChannelGuildForum || c.Type == ChannelGuildMedia
+}
+
+// ForumTag is a tag that can be applied to posts in a forum or media channel.
+type ForumTag struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Moderated bool   `json:"moderated"`
+	EmojiID   string `json:"emoji_id,omitempty"`
+	EmojiName string `json:"emoji_name,omitempty"`
+}
+
+// ThreadMetadata holds the archive state of a thread.
+type ThreadMetadata struct {
+	Archived            bool   `json:"archived"`
+	AutoArchiveDuration int    `json:"auto_archive_duration"`
+	ArchiveTimestamp    string `json:"archive_timestamp"`
+	Locked              bool   `json:"locked"`
+	Invitable           bool   `json:"invitable,omitempty"`
+	CreateTimestamp     string `json:"create_timestamp,omitempty"`
 }
 
 // Guild NSFW levels
@@ -79,3 +160,349 @@ type Guild struct {
 	NSFWLevel   int    `json:"nsfw_level"`
 	Description string `json:"description"`
 }
+
+// CreatedAt returns when the guild was created, the zero time if the ID is invalid.
+func (g Guild) CreatedAt() time.Time {
+	return createdAt(g.ID)
+}
+
+// Role is a guild role.
+type Role struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Color    int    `json:"color"`
+	Position int    `json:"position"`
+	Managed  bool   `json:"managed"`
+}
+
+// Message types
+const (
+	MessageDefault              = 0
+	MessageRecipientAdd         = 1
+	MessageRecipientRemove      = 2
+	MessageCall                 = 3
+	MessageChannelNameChange    = 4
+	MessageChannelIconChange    = 5
+	MessageChannelPinnedMessage = 6
+	MessageUserJoin             = 7
+	MessageGuildBoost           = 8
+	MessageGuildBoostTier1      = 9
+	MessageGuildBoostTier2      = 10
+	MessageGuildBoostTier3      = 11
+	MessageChannelFollowAdd     = 12
+	MessageThreadCreated        = 18
+	MessageReply                = 19
+	MessageChatInputCommand     = 20
+	MessageThreadStarterMessage = 21
+	MessageContextMenuCommand   = 23
+	MessageAutoModerationAction = 24
+	MessageStageStart           = 27
+	MessageStageEnd             = 28
+	MessageStageTopic           = 31
+	MessagePollResult           = 46
+)
+
+// Message flags
+const (
+	MessageFlagCrossposted          = 1 << 0
+	MessageFlagIsCrosspost          = 1 << 1
+	MessageFlagSuppressEmbeds       = 1 << 2
+	MessageFlagSourceMessageDeleted = 1 << 3
+	MessageFlagUrgent               = 1 << 4
+	MessageFlagHasThread            = 1 << 5
+	MessageFlagEphemeral            = 1 << 6
+	MessageFlagLoading              = 1 << 7
+	MessageFlagSuppressNotification = 1 << 12
+	MessageFlagIsVoiceMessage       = 1 << 13
+)
+
+// Message is a Discord message.
+// Fields not modelled here are kept in Raw and written back out when the message is marshalled.
+type Message struct {
+	ID                string            `json:"id"`
+	ChannelID         string            `json:"channel_id"`
+	GuildID           string            `json:"guild_id,omitempty"`
+	Type              int               `json:"type"`
+	Author            User              `json:"author"`
+	Content           string            `json:"content"`
+	Timestamp         string            `json:"timestamp"`
+	EditedTimestamp   string            `json:"edited_timestamp,omitempty"`
+	Pinned            bool              `json:"pinned"`
+	Flags             int               `json:"flags"`
+	Attachments       []Attachment      `json:"attachments"`
+	Embeds            []Embed           `json:"embeds"`
+	Reactions         []Reaction        `json:"reactions,omitempty"`
+	Mentions          []User            `json:"mentions"`
+	MentionRoles      []string          `json:"mention_roles"`
+	MentionEveryone   bool              `json:"mention_everyone"`
+	MessageReference  *MessageReference `json:"message_reference,omitempty"`
+	ReferencedMessage *Message          `json:"referenced_message,omitempty"`
+	Thread            *Channel          `json:"thread,omitempty"`
+	StickerItems      []StickerItem     `json:"sticker_items,omitempty"`
+
+	// Raw is the message exactly as returned by the API
+	Raw json.RawMessage `json:"-"`
+}
+
+// messageFields is used to (un)marshal Message without recursing into its own methods.
+type messageFields Message
+
+// UnmarshalJSON decodes a message and keeps the original JSON in Raw.
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var fields messageFields
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	*m = Message(fields)
+	m.Raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+// MarshalJSON encodes a message as it was received, with any modelled fields that have been
+// changed since, such as attachment URLs rewritten to local copies, updated in place.
+// Nested objects keep the fields that aren't modelled, so nothing is lost on export.
+func (m Message) MarshalJSON() ([]byte, error) {
+	current, err := json.Marshal(messageFields(m))
+	if err != nil {
+		return nil, err
+	}
+	if len(m.Raw) == 0 {
+		return current, nil
+	}
+
+	// The modelled fields as received, to tell which ones have changed
+	var received messageFields
+	if err := json.Unmarshal(m.Raw, &received); err != nil {
+		return current, nil
+	}
+	original, err := json.Marshal(received)
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeJSON(m.Raw, original, current), nil
+}
+
+// mergeJSON returns raw with the changes from original to current applied: values current
+// doesn't change are kept from raw as they are, objects and equally long arrays are merged
+// member by member, anything else changed is replaced. It returns nil if the value was removed.
+func mergeJSON(raw, original, current json.RawMessage) json.RawMessage {
+	if bytes.Equal(original, current) {
+		return raw
+	}
+
+	var rawObj, origObj, curObj map[string]json.RawMessage
+	if json.Unmarshal(raw, &rawObj) == nil && json.Unmarshal(original, &origObj) == nil && json.Unmarshal(current, &curObj) == nil &&
+		rawObj != nil && origObj != nil && curObj != nil {
+		keys := make(map[string]bool)
+		for k := range origObj {
+			keys[k] = true
+		}
+		for k := range curObj {
+			keys[k] = true
+		}
+		for k := range keys {
+			if v := mergeJSON(rawObj[k], origObj[k], curObj[k]); v != nil {
+				rawObj[k] = v
+			} else {
+				delete(rawObj, k)
+			}
+		}
+		merged, err := json.Marshal(rawObj)
+		if err != nil {
+			return current
+		}
+		return merged
+	}
+
+	var rawArr, origArr, curArr []json.RawMessage
+	if json.Unmarshal(raw, &rawArr) == nil && json.Unmarshal(original, &origArr) == nil && json.Unmarshal(current, &curArr) == nil &&
+		len(rawArr) == len(curArr) && len(origArr) == len(curArr) {
+		for i := range rawArr {
+			if v := mergeJSON(rawArr[i], origArr[i], curArr[i]); v != nil {
+				rawArr[i] = v
+			} else {
+				rawArr[i] = json.RawMessage("null")
+			}
+		}
+		merged, err := json.Marshal(rawArr)
+		if err != nil {
+			return current
+		}
+		return merged
+	}
+
+	return current
+}
+
+// IsEdited reports whether the message has been edited.
+func (m Message) IsEdited() bool {
+	return m.EditedTimestamp != ""
+}
+
+// JumpURL returns the link that opens the message in Discord. GuildID must be set for guild messages.
+func (m Message) JumpURL() string {
+	return JumpURL(m.GuildID, m.ChannelID, m.ID)
+}
+
+// JumpURL returns the link that opens a message in Discord, guildID is empty for DMs.
+func JumpURL(guildID, channelID, messageID string) string {
+	if guildID == "" {
+		guildID = "@me"
+	}
+	return fmt.Sprintf("https://discord.com/channels/%s/%s/%s", guildID, channelID, messageID)
+}
+
+// MessageReference points to the message a reply, crosspost or pin notice refers to.
+type MessageReference struct {
+	Type      int    `json:"type,omitempty"`
+	MessageID string `json:"message_id,omitempty"`
+	ChannelID string `json:"channel_id,omitempty"`
+	GuildID   string `json:"guild_id,omitempty"`
+}
+
+// Attachment is a file attached to a message.
+type Attachment struct {
+	ID          string `json:"id"`
+	Filename    string `json:"filename"`
+	Description string `json:"description,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Size        int64  `json:"size"`
+	URL         string `json:"url"`
+	ProxyURL    string `json:"proxy_url"`
+	Height      int    `json:"height,omitempty"`
+	Width       int    `json:"width,omitempty"`
+}
+
+// Embed is rich content embedded in a message.
+type Embed struct {
+	Type        string         `json:"type,omitempty"`
+	Title       string         `json:"title,omitempty"`
+	Description string         `json:"description,omitempty"`
+	URL         string         `json:"url,omitempty"`
+	Timestamp   string         `json:"timestamp,omitempty"`
+	Color       int            `json:"color,omitempty"`
+	Footer      *EmbedFooter   `json:"footer,omitempty"`
+	Image       *EmbedMedia    `json:"image,omitempty"`
+	Thumbnail   *EmbedMedia    `json:"thumbnail,omitempty"`
+	Video       *EmbedMedia    `json:"video,omitempty"`
+	Provider    *EmbedProvider `json:"provider,omitempty"`
+	Author      *EmbedAuthor   `json:"author,omitempty"`
+	Fields      []EmbedField   `json:"fields,omitempty"`
+}
+
+// EmbedFooter is the footer of an embed.
+type EmbedFooter struct {
+	Text    string `json:"text"`
+	IconURL string `json:"icon_url,omitempty"`
+}
+
+// EmbedMedia is an image, thumbnail or video of an embed.
+type EmbedMedia struct {
+	URL      string `json:"url"`
+	ProxyURL string `json:"proxy_url,omitempty"`
+	Height   int    `json:"height,omitempty"`
+	Width    int    `json:"width,omitempty"`
+}
+
+// EmbedProvider is the site an embed was generated from.
+type EmbedProvider struct {
+	Name string `json:"name,omitempty"`
+	URL  string `json:"url,omitempty"`
+}
+
+// EmbedAuthor is the author shown on an embed.
+type EmbedAuthor struct {
+	Name    string `json:"name"`
+	URL     string `json:"url,omitempty"`
+	IconURL string `json:"icon_url,omitempty"`
+}
+
+// EmbedField is a name/value field of an embed.
+type EmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+// Sticker formats
+const (
+	StickerFormatPNG    = 1
+	StickerFormatAPNG   = 2
+	StickerFormatLottie = 3
+	StickerFormatGIF    = 4
+)
+
+// StickerItem is the partial sticker sent with a message.
+type StickerItem struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	FormatType int    `json:"format_type"`
+
+	// LocalPath is set when the sticker has been downloaded alongside an export
+	LocalPath string `json:"local_path,omitempty"`
+}
+
+// URL returns the image (or Lottie JSON) URL of the sticker, or its local copy if downloaded.
+func (s StickerItem) URL() string {
+	if s.LocalPath != "" {
+		return s.LocalPath
+	}
+	switch s.FormatType {
+	case StickerFormatLottie:
+		return fmt.Sprintf("%s/stickers/%s.json", CDNURL, s.ID)
+	case StickerFormatGIF:
+		return fmt.Sprintf("https://media.discordapp.net/stickers/%s.gif", s.ID)
+	default:
+		return fmt.Sprintf("%s/stickers/%s.png", CDNURL, s.ID)
+	}
+}
+
+// Emoji is a partial emoji object, either unicode (no ID) or custom.
+type Emoji struct {
+	ID       string `json:"id,omitempty"`
+	Name     string `json:"name"`
+	Animated bool   `json:"animated,omitempty"`
+}
+
+// APIName returns the emoji as the reaction endpoints identify it: name:id for custom emoji,
+// the unicode character otherwise.
+func (e Emoji) APIName() string {
+	if e.ID == "" {
+		return e.Name
+	}
+	return e.Name + ":" + e.ID
+}
+
+// URL returns the image URL of a custom emoji, or "" for unicode emoji.
+func (e Emoji) URL() string {
+	if e.ID == "" {
+		return ""
+	}
+	ext := "png"
+	if e.Animated {
+		ext = "gif"
+	}
+	return fmt.Sprintf("%s/emojis/%s.%s", CDNURL, e.ID, ext)
+}
+
+// Reaction is the summary of one emoji's reactions on a message.
+type Reaction struct {
+	Count        int                  `json:"count"`
+	CountDetails ReactionCountDetails `json:"count_details"`
+	Me           bool                 `json:"me"`
+	MeBurst      bool                 `json:"me_burst"`
+	Emoji        Emoji                `json:"emoji"`
+	BurstColors  []string             `json:"burst_colors,omitempty"`
+
+	// Users and BurstUsers list who reacted, normally and with burst (super) reactions.
+	// The API doesn't include them with messages, they are filled in by exports that ask for them.
+	Users      []User `json:"users,omitempty"`
+	BurstUsers []User `json:"burst_users,omitempty"`
+}
+
+// ReactionCountDetails splits a reaction count into normal and burst (super) reactions.
+type ReactionCountDetails struct {
+	Burst  int `json:"burst"`
+	Normal int `json:"normal"`
+}
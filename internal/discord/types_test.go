@@ -0,0 +1,99 @@
+package discord
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+const rawMessage = `{
+	"id": "1001",
+	"channel_id": "100",
+	"type": 0,
+	"content": "hello",
+	"timestamp": "2025-01-02T12:00:00.000000+00:00",
+	"edited_timestamp": null,
+	"pinned": false,
+	"flags": 8192,
+	"tts": false,
+	"author": {"id": "1", "username": "alice", "global_name": null, "avatar": null, "discriminator": "0", "bot": true, "public_flags": 64},
+	"attachments": [{"id": "9", "filename": "voice-message.ogg", "size": 1234, "url": "https://cdn.example/voice.ogg", "duration_secs": 3.5, "waveform": "AAAA"}],
+	"components": [{"type": 1, "components": []}]
+}`
+
+func decodeJSON(t *testing.T, data []byte) map[string]any {
+	t.Helper()
+	var v map[string]any
+	if err := json.Unmarshal(data, &v); err != nil {
+		t.Fatalf("invalid JSON %s: %v", data, err)
+	}
+	return v
+}
+
+func TestMessageRoundTrip(t *testing.T) {
+	var m Message
+	if err := json.Unmarshal([]byte(rawMessage), &m); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := decodeJSON(t, []byte(rawMessage))
+	if got := decodeJSON(t, out); !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip changed the message\ngot:  %s\nwant: %s", out, rawMessage)
+	}
+}
+
+func TestMessageMarshalKeepsChanges(t *testing.T) {
+	var m Message
+	if err := json.Unmarshal([]byte(rawMessage), &m); err != nil {
+		t.Fatal(err)
+	}
+
+	// As the downloader does, on a copy of the slice
+	m.Attachments = append([]Attachment(nil), m.Attachments...)
+	m.Attachments[0].URL = "files/ab/abcd.ogg"
+	m.Content = "edited"
+
+	out, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := decodeJSON(t, out)
+
+	if got["content"] != "edited" {
+		t.Errorf("content = %v, want edited", got["content"])
+	}
+	attachment := got["attachments"].([]any)[0].(map[string]any)
+	if attachment["url"] != "files/ab/abcd.ogg" {
+		t.Errorf("attachment url = %v, want the local path", attachment["url"])
+	}
+	if attachment["waveform"] != "AAAA" || attachment["duration_secs"] != 3.5 {
+		t.Errorf("attachment lost its unmodelled fields: %v", attachment)
+	}
+	if _, ok := attachment["proxy_url"]; ok {
+		t.Errorf("attachment gained a proxy_url that wasn't received: %v", attachment)
+	}
+	if author := got["author"].(map[string]any); author["bot"] != true || author["discriminator"] != "0" {
+		t.Errorf("author lost its unmodelled fields: %v", author)
+	}
+	for _, key := range []string{"embeds", "mentions", "mention_roles"} {
+		if _, ok := got[key]; ok {
+			t.Errorf("message gained %q, which wasn't received", key)
+		}
+	}
+}
+
+func TestMessageMarshalWithoutRaw(t *testing.T) {
+	m := Message{ID: "1", ChannelID: "2", Content: "hi"}
+	out, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := decodeJSON(t, out); got["content"] != "hi" || got["id"] != "1" {
+		t.Errorf("unexpected encoding %s", out)
+	}
+}
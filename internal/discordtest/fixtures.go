@@ -0,0 +1,59 @@
+package discordtest
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/CaptainFallaway/Discorder/internal/discord"
+)
+
+// fixtureEpoch is the timestamp of the first generated message
+var fixtureEpoch = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// NewUser returns a user fixture.
+func NewUser(id, username, globalName string) discord.User {
+	return discord.User{ID: id, Username: username, GlobalName: globalName}
+}
+
+// NewMessage returns a default message fixture.
+func NewMessage(id, channelID string, author discord.User, content string, timestamp time.Time) discord.Message {
+	return discord.Message{
+		ID:          id,
+		ChannelID:   channelID,
+		Type:        discord.MessageDefault,
+		Author:      author,
+		Content:     content,
+		Timestamp:   timestamp.UTC().Format(time.RFC3339Nano),
+		Attachments: []discord.Attachment{},
+		Embeds:      []discord.Embed{},
+		Mentions:    []discord.User{},
+	}
+}
+
+// GenerateMessages returns n sequential messages for a channel, oldest first,
+// with IDs starting at firstID and one minute between messages.
+func GenerateMessages(channelID string, author discord.User, firstID uint64, n int) []discord.Message {
+	messages := make([]discord.Message, 0, n)
+	for i := range n {
+		id := strconv.FormatUint(firstID+uint64(i), 10)
+		ts := fixtureEpoch.Add(time.Duration(i) * time.Minute)
+		messages = append(messages, NewMessage(id, channelID, author, "message "+strconv.Itoa(i+1), ts))
+	}
+	return messages
+}
+
+// NewThread returns a public thread fixture of a channel. A non-zero archivedAt archives it.
+func NewThread(id, parentID, name string, archivedAt time.Time) discord.Channel {
+	thread := discord.Channel{
+		ID:             id,
+		Type:           discord.ChannelPublicThread,
+		ParentID:       parentID,
+		Name:           name,
+		ThreadMetadata: &discord.ThreadMetadata{AutoArchiveDuration: 1440},
+	}
+	if !archivedAt.IsZero() {
+		thread.ThreadMetadata.Archived = true
+		thread.ThreadMetadata.ArchiveTimestamp = archivedAt.UTC().Format("2006-01-02T15:04:05.000000+00:00")
+	}
+	return thread
+}
@@ -0,0 +1,560 @@
+// Package discordtest provides an in-memory fake of the Discord API for offline tests.
+package discordtest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/CaptainFallaway/Discorder/internal/discord"
+)
+
+// Server is an httptest-backed fake of the Discord API serving in-memory fixtures.
+// Fixtures can be modified at any time; the server locks around every request.
+type Server struct {
+	*httptest.Server
+
+	mu sync.Mutex
+
+	// Token, if set, must match the Authorization header of every request
+	Token string
+
+	Relationships []discord.Relationship
+	UserChannels  []discord.Channel
+	Guilds        []discord.Guild
+	GuildChannels map[string][]discord.Channel // guild ID -> channels
+	GuildRoles    map[string][]discord.Role    // guild ID -> roles
+	Messages      map[string][]discord.Message // channel ID -> messages, in any order
+	Threads       map[string][]discord.Channel // parent channel ID -> threads, archived per their metadata
+
+	// Encoding compresses responses: "", "gzip" or "deflate"
+	Encoding string
+
+	// Requests records every request received, in order
+	Requests []RecordedRequest
+
+	failures     []failure
+	pathFailures map[string]failure
+	reactions    map[reactionKey][]discord.User
+}
+
+// reactionKey identifies the users who reacted to a message with an emoji
+type reactionKey struct {
+	messageID string
+	emoji     string // As in the API path, name or name:id
+	burst     bool
+}
+
+// RecordedRequest is a request received by the fake server.
+type RecordedRequest struct {
+	Method string
+	Path   string
+	Query  string
+}
+
+// failure is a canned error response.
+type failure struct {
+	status     int
+	code       int
+	message    string
+	retryAfter time.Duration
+	global     bool
+}
+
+// NewServer starts a fake Discord API server. Close it when done.
+func NewServer() *Server {
+	s := &Server{
+		GuildChannels: make(map[string][]discord.Channel),
+		GuildRoles:    make(map[string][]discord.Role),
+		Messages:      make(map[string][]discord.Message),
+		Threads:       make(map[string][]discord.Channel),
+		pathFailures:  make(map[string]failure),
+		reactions:     make(map[reactionKey][]discord.User),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/{version}/users/@me/relationships", s.handleRelationships)
+	mux.HandleFunc("GET /api/{version}/users/@me/channels", s.handleUserChannels)
+	mux.HandleFunc("POST /api/{version}/users/@me/channels", s.handleCreateDM)
+	mux.HandleFunc("GET /api/{version}/users/@me/guilds", s.handleGuilds)
+	mux.HandleFunc("GET /api/{version}/guilds/{guild}/channels", s.handleGuildChannels)
+	mux.HandleFunc("GET /api/{version}/guilds/{guild}/roles", s.handleGuildRoles)
+	mux.HandleFunc("GET /api/{version}/guilds/{guild}/threads/active", s.handleActiveThreads)
+	mux.HandleFunc("GET /api/{version}/channels/{channel}/messages", s.handleMessages)
+	mux.HandleFunc("GET /api/{version}/channels/{channel}/pins", s.handlePins)
+	mux.HandleFunc("GET /api/{version}/channels/{channel}/messages/{message}/reactions/{emoji}", s.handleReactions)
+	mux.HandleFunc("GET /api/{version}/channels/{channel}/threads/archived/{visibility}", s.handleArchivedThreads)
+	mux.HandleFunc("GET /api/{version}/channels/{channel}", s.handleGetChannel)
+	mux.HandleFunc("DELETE /api/{version}/channels/{channel}", s.handleDeleteChannel)
+
+	s.Server = httptest.NewServer(s.middleware(mux))
+	return s
+}
+
+// BaseURL returns the API root to pass to discord.WithBaseURL.
+func (s *Server) BaseURL() string {
+	return s.URL + "/api"
+}
+
+// Client returns a DiscordClient pointed at the fake server.
+func (s *Server) Client(opts ...discord.Option) *discord.DiscordClient {
+	opts = append([]discord.Option{discord.WithBaseURL(s.BaseURL()), discord.WithHTTPClient(s.Server.Client())}, opts...)
+	return discord.NewDiscordClient(s.Token, false, opts...)
+}
+
+// AddMessages appends messages to a channel's fixtures.
+func (s *Server) AddMessages(channelID string, messages ...discord.Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Messages[channelID] = append(s.Messages[channelID], messages...)
+}
+
+// AddReactions records users as having reacted to a message with an emoji, served by the reactions endpoint.
+// The message's own reaction counts are not updated.
+func (s *Server) AddReactions(messageID string, emoji discord.Emoji, burst bool, users ...discord.User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := reactionKey{messageID: messageID, emoji: emoji.APIName(), burst: burst}
+	s.reactions[key] = append(s.reactions[key], users...)
+}
+
+// RateLimitNext makes the next n requests fail with 429 Too Many Requests.
+func (s *Server) RateLimitNext(n int, retryAfter time.Duration, global bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for range n {
+		s.failures = append(s.failures, failure{
+			status:     http.StatusTooManyRequests,
+			message:    "You are being rate limited.",
+			retryAfter: retryAfter,
+			global:     global,
+		})
+	}
+}
+
+// FailNext makes the next n requests fail with the given HTTP status, e.g. a 5xx.
+func (s *Server) FailNext(n int, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for range n {
+		s.failures = append(s.failures, failure{status: status, message: http.StatusText(status)})
+	}
+}
+
+// FailPath makes every request to an API path (without the /api/{version} prefix)
+// fail with the given HTTP status and Discord JSON error code.
+func (s *Server) FailPath(path string, status, code int, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pathFailures[path] = failure{status: status, code: code, message: message}
+}
+
+// RequestCount returns how many requests have been received.
+func (s *Server) RequestCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.Requests)
+}
+
+// middleware records requests, checks auth, injects canned failures and compresses responses.
+func (s *Server) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		apiPath := stripAPIPrefix(r.URL.Path)
+		s.Requests = append(s.Requests, RecordedRequest{Method: r.Method, Path: apiPath, Query: r.URL.RawQuery})
+
+		if s.Token != "" && r.Header.Get("Authorization") != s.Token {
+			writeError(w, failure{status: http.StatusUnauthorized, code: 0, message: "401: Unauthorized"})
+			return
+		}
+
+		if len(s.failures) > 0 {
+			f := s.failures[0]
+			s.failures = s.failures[1:]
+			writeError(w, f)
+			return
+		}
+
+		if f, ok := s.pathFailures[apiPath]; ok {
+			writeError(w, f)
+			return
+		}
+
+		rec := httptest.NewRecorder()
+		next.ServeHTTP(rec, r)
+
+		body := rec.Body.Bytes()
+		for k, v := range rec.Header() {
+			w.Header()[k] = v
+		}
+		w.Header().Set("X-RateLimit-Bucket", bucketFor(apiPath))
+		w.Header().Set("X-RateLimit-Limit", "50")
+		w.Header().Set("X-RateLimit-Remaining", "49")
+		w.Header().Set("X-RateLimit-Reset-After", "0.001")
+
+		if s.Encoding != "" && rec.Code >= 200 && rec.Code < 300 {
+			compressed, err := compress(s.Encoding, body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			body = compressed
+			w.Header().Set("Content-Encoding", s.Encoding)
+		}
+
+		w.WriteHeader(rec.Code)
+		w.Write(body)
+	})
+}
+
+func (s *Server) handleRelationships(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, nonNil(s.Relationships))
+}
+
+func (s *Server) handleUserChannels(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, nonNil(s.UserChannels))
+}
+
+func (s *Server) handleCreateDM(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		RecipientID string `json:"recipient_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.RecipientID == "" {
+		writeError(w, failure{status: http.StatusBadRequest, code: 50035, message: "Invalid Form Body"})
+		return
+	}
+
+	for _, c := range s.UserChannels {
+		if c.Type == discord.ChannelDM && len(c.Recipients) == 1 && c.Recipients[0].ID == payload.RecipientID {
+			writeJSON(w, http.StatusOK, c)
+			return
+		}
+	}
+
+	channel := discord.Channel{
+		ID:         strconv.Itoa(1000000 + len(s.UserChannels)),
+		Type:       discord.ChannelDM,
+		Recipients: []discord.User{{ID: payload.RecipientID}},
+	}
+	s.UserChannels = append(s.UserChannels, channel)
+	writeJSON(w, http.StatusOK, channel)
+}
+
+func (s *Server) handleGetChannel(w http.ResponseWriter, r *http.Request) {
+	if c, ok := s.findChannel(r.PathValue("channel")); ok {
+		writeJSON(w, http.StatusOK, c)
+		return
+	}
+	writeError(w, failure{status: http.StatusNotFound, code: 10003, message: "Unknown Channel"})
+}
+
+// findChannel looks a channel up among the user's and every guild's channels.
+func (s *Server) findChannel(id string) (discord.Channel, bool) {
+	for _, c := range s.UserChannels {
+		if c.ID == id {
+			return c, true
+		}
+	}
+	for _, channels := range s.GuildChannels {
+		for _, c := range channels {
+			if c.ID == id {
+				return c, true
+			}
+		}
+	}
+	for _, threads := range s.Threads {
+		for _, c := range threads {
+			if c.ID == id {
+				return c, true
+			}
+		}
+	}
+	return discord.Channel{}, false
+}
+
+func (s *Server) handleDeleteChannel(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("channel")
+	for i, c := range s.UserChannels {
+		if c.ID == id {
+			s.UserChannels = slices.Delete(s.UserChannels, i, i+1)
+			writeJSON(w, http.StatusOK, c)
+			return
+		}
+	}
+	writeError(w, failure{status: http.StatusNotFound, code: 10003, message: "Unknown Channel"})
+}
+
+func (s *Server) handleGuilds(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, nonNil(s.Guilds))
+}
+
+func (s *Server) handleGuildChannels(w http.ResponseWriter, r *http.Request) {
+	channels, ok := s.GuildChannels[r.PathValue("guild")]
+	if !ok {
+		writeError(w, failure{status: http.StatusNotFound, code: 10004, message: "Unknown Guild"})
+		return
+	}
+	writeJSON(w, http.StatusOK, nonNil(channels))
+}
+
+// handlePins serves a channel's pinned messages.
+func (s *Server) handlePins(w http.ResponseWriter, r *http.Request) {
+	pins := make([]discord.Message, 0)
+	for _, m := range s.Messages[r.PathValue("channel")] {
+		if m.Pinned {
+			pins = append(pins, m)
+		}
+	}
+	// Approximates most recently pinned first
+	slices.SortFunc(pins, func(a, b discord.Message) int { return discord.CompareIDs(b.ID, a.ID) })
+	writeJSON(w, http.StatusOK, pins)
+}
+
+func (s *Server) handleReactions(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	limit := 25
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 100 {
+			writeError(w, failure{status: http.StatusBadRequest, code: 50035, message: "Invalid Form Body"})
+			return
+		}
+		limit = n
+	}
+
+	key := reactionKey{messageID: r.PathValue("message"), emoji: r.PathValue("emoji"), burst: q.Get("type") == "1"}
+	users := slices.Clone(s.reactions[key])
+	slices.SortFunc(users, func(a, b discord.User) int { return discord.CompareIDs(a.ID, b.ID) })
+
+	page := make([]discord.User, 0, limit)
+	for _, u := range users {
+		if after := q.Get("after"); after != "" && discord.CompareIDs(u.ID, after) <= 0 {
+			continue
+		}
+		if len(page) < limit {
+			page = append(page, u)
+		}
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (s *Server) handleActiveThreads(w http.ResponseWriter, r *http.Request) {
+	channels, ok := s.GuildChannels[r.PathValue("guild")]
+	if !ok {
+		writeError(w, failure{status: http.StatusNotFound, code: 10004, message: "Unknown Guild"})
+		return
+	}
+
+	active := make([]discord.Channel, 0)
+	for _, c := range channels {
+		for _, t := range s.Threads[c.ID] {
+			if t.ThreadMetadata == nil || !t.ThreadMetadata.Archived {
+				active = append(active, t)
+			}
+		}
+	}
+	slices.SortFunc(active, func(a, b discord.Channel) int { return discord.CompareIDs(a.ID, b.ID) })
+	writeJSON(w, http.StatusOK, discord.ThreadList{Threads: active})
+}
+
+func (s *Server) handleArchivedThreads(w http.ResponseWriter, r *http.Request) {
+	private := r.PathValue("visibility") == "private"
+	q := r.URL.Query()
+
+	limit := 50
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 2 || n > 100 {
+			writeError(w, failure{status: http.StatusBadRequest, code: 50035, message: "Invalid Form Body"})
+			return
+		}
+		limit = n
+	}
+
+	var archived []discord.Channel
+	for _, t := range s.Threads[r.PathValue("channel")] {
+		if t.ThreadMetadata == nil || !t.ThreadMetadata.Archived || (t.Type == discord.ChannelPrivateThread) != private {
+			continue
+		}
+		// RFC3339 timestamps in the same zone compare lexically
+		if before := q.Get("before"); before != "" && t.ThreadMetadata.ArchiveTimestamp >= before {
+			continue
+		}
+		archived = append(archived, t)
+	}
+
+	// Most recently archived first
+	slices.SortFunc(archived, func(a, b discord.Channel) int {
+		return strings.Compare(b.ThreadMetadata.ArchiveTimestamp, a.ThreadMetadata.ArchiveTimestamp)
+	})
+
+	list := discord.ThreadList{Threads: nonNil(archived[:min(limit, len(archived))]), HasMore: len(archived) > limit}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (s *Server) handleGuildRoles(w http.ResponseWriter, r *http.Request) {
+	roles, ok := s.GuildRoles[r.PathValue("guild")]
+	if !ok {
+		writeError(w, failure{status: http.StatusNotFound, code: 10004, message: "Unknown Guild"})
+		return
+	}
+	writeJSON(w, http.StatusOK, nonNil(roles))
+}
+
+// handleMessages serves a channel's messages newest first, paginated like Discord
+// by one of before, after or around, with limit capped at 100.
+func (s *Server) handleMessages(w http.ResponseWriter, r *http.Request) {
+	messages, ok := s.Messages[r.PathValue("channel")]
+	if !ok {
+		writeError(w, failure{status: http.StatusNotFound, code: 10003, message: "Unknown Channel"})
+		return
+	}
+
+	q := r.URL.Query()
+	limit := 50
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 100 {
+			writeError(w, failure{status: http.StatusBadRequest, code: 50035, message: "Invalid Form Body"})
+			return
+		}
+		limit = n
+	}
+
+	// Newest first
+	sorted := slices.Clone(messages)
+	slices.SortFunc(sorted, func(a, b discord.Message) int { return discord.CompareIDs(b.ID, a.ID) })
+
+	var page []discord.Message
+	switch {
+	case q.Get("before") != "":
+		before := q.Get("before")
+		for _, m := range sorted {
+			if discord.CompareIDs(m.ID, before) < 0 && len(page) < limit {
+				page = append(page, m)
+			}
+		}
+	case q.Get("after") != "":
+		after := q.Get("after")
+		// The oldest messages after the ID, still returned newest first
+		i := len(sorted)
+		for i > 0 && discord.CompareIDs(sorted[i-1].ID, after) <= 0 {
+			i--
+		}
+		start := max(0, i-limit)
+		page = sorted[start:i]
+	case q.Get("around") != "":
+		around := q.Get("around")
+		idx := slices.IndexFunc(sorted, func(m discord.Message) bool { return discord.CompareIDs(m.ID, around) <= 0 })
+		if idx < 0 {
+			idx = len(sorted)
+		}
+		start := max(0, idx-limit/2)
+		end := min(len(sorted), start+limit)
+		page = sorted[start:end]
+	default:
+		page = sorted[:min(limit, len(sorted))]
+	}
+
+	writeJSON(w, http.StatusOK, nonNil(page))
+}
+
+// stripAPIPrefix removes the /api/{version} prefix from a request path.
+func stripAPIPrefix(path string) string {
+	parts := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 3)
+	if len(parts) == 3 && parts[0] == "api" {
+		return "/" + parts[2]
+	}
+	return path
+}
+
+// bucketFor returns a fake rate limit bucket hash for an API path.
+func bucketFor(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for i := 1; i < len(parts); i++ {
+		if _, err := strconv.ParseUint(parts[i], 10, 64); err == nil && parts[i-1] != "channels" && parts[i-1] != "guilds" {
+			parts[i] = "id"
+		}
+	}
+	if len(parts) >= 2 && (parts[0] == "channels" || parts[0] == "guilds") {
+		parts[1] = "major"
+	}
+	return strings.Join(parts, ".")
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes a Discord-style JSON error response.
+func writeError(w http.ResponseWriter, f failure) {
+	if f.status == http.StatusTooManyRequests {
+		seconds := f.retryAfter.Seconds()
+		w.Header().Set("Retry-After", strconv.FormatFloat(seconds, 'f', -1, 64))
+		if f.global {
+			w.Header().Set("X-RateLimit-Global", "true")
+			w.Header().Set("X-RateLimit-Scope", "global")
+		} else {
+			w.Header().Set("X-RateLimit-Scope", "user")
+		}
+		writeJSON(w, f.status, map[string]any{
+			"message":     f.message,
+			"retry_after": seconds,
+			"global":      f.global,
+		})
+		return
+	}
+
+	writeJSON(w, f.status, map[string]any{
+		"message": f.message,
+		"code":    f.code,
+	})
+}
+
+// compress encodes body with the given Content-Encoding.
+func compress(encoding string, body []byte) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	switch encoding {
+	case "gzip":
+		zw := gzip.NewWriter(buf)
+		if _, err := zw.Write(body); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+	case "deflate":
+		zw := zlib.NewWriter(buf)
+		if _, err := zw.Write(body); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported encoding %q", encoding)
+	}
+	return buf.Bytes(), nil
+}
+
+// nonNil returns an empty slice instead of nil so it encodes as [] rather than null.
+func nonNil[T any](v []T) []T {
+	if v == nil {
+		return []T{}
+	}
+	return v
+}
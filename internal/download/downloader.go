@@ -0,0 +1,360 @@
+// Package download fetches message attachments, embed images and stickers into a local,
+// content-addressed directory so exports keep working after Discord's URLs expire.
+package download
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrTooLarge is returned for files over the downloader's size cap
+var ErrTooLarge = errors.New("file exceeds the maximum download size")
+
+// indexFile records every completed download, relative to the download directory
+const indexFile = "index.json"
+
+// partialDir holds incomplete downloads so they can be resumed
+const partialDir = ".partial"
+
+// Downloader fetches files into Dir, naming each by the SHA-256 of its content:
+//
+//	<dir>/<first two hex digits>/<sha256><ext>
+//
+// Identical files are stored once. Interrupted downloads are resumed with range requests,
+// and completed files are verified against their checksum before being reused.
+type Downloader struct {
+	// Dir is the root of the content-addressed store
+	Dir string
+	// Concurrency limits parallel downloads, defaults to 4
+	Concurrency int
+	// MaxSize skips files larger than this many bytes, 0 means no limit
+	MaxSize int64
+	// Client makes the requests, defaults to a client with a generous timeout
+	Client *http.Client
+
+	mu    sync.Mutex
+	index map[string]Entry
+	stats Stats
+	// fetching holds a lock per key being fetched, as fetches of a key share its partial file
+	fetching map[string]*keyLock
+}
+
+// keyLock serialises the fetches of one key
+type keyLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// Entry is a completed download.
+type Entry struct {
+	Path   string `json:"path"` // Relative to Dir, with forward slashes
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+	URL    string `json:"url"`
+}
+
+// Stats counts what a Downloader has done.
+type Stats struct {
+	Downloaded int
+	Reused     int
+	Skipped    int
+	Failed     int
+	Bytes      int64
+}
+
+// NewDownloader returns a Downloader storing files in dir, loading its index of previous downloads.
+func NewDownloader(dir string) (*Downloader, error) {
+	if err := os.MkdirAll(filepath.Join(dir, partialDir), 0o755); err != nil {
+		return nil, fmt.Errorf("error creating download directory: %w", err)
+	}
+
+	d := &Downloader{
+		Dir:         dir,
+		Concurrency: 4,
+		Client:      &http.Client{Timeout: 10 * time.Minute},
+		index:       make(map[string]Entry),
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, indexFile))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("error reading download index: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(b, &d.index); err != nil {
+			return nil, fmt.Errorf("error parsing download index: %w", err)
+		}
+	}
+
+	return d, nil
+}
+
+// Stats returns the counts of downloads so far.
+func (d *Downloader) Stats() Stats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.stats
+}
+
+// SaveIndex writes the index of completed downloads.
+func (d *Downloader) SaveIndex() error {
+	d.mu.Lock()
+	b, err := json.MarshalIndent(d.index, "", "  ")
+	d.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(d.Dir, indexFile+".tmp")
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return fmt.Errorf("error writing download index: %w", err)
+	}
+	return os.Rename(tmp, filepath.Join(d.Dir, indexFile))
+}
+
+// Fetch downloads rawURL, identified by key across runs (URLs with expiring signatures change,
+// keys such as attachment IDs don't). expectedSize, if known, is checked against the result.
+// Files already downloaded and still matching their checksum are reused without a request.
+// Concurrent fetches of the same key wait for each other, so the later ones reuse the file.
+func (d *Downloader) Fetch(ctx context.Context, key, rawURL string, expectedSize int64) (Entry, error) {
+	unlock := d.lockKey(key)
+	defer unlock()
+
+	if entry, ok := d.lookup(key); ok {
+		if d.verify(entry) == nil {
+			d.count(func(s *Stats) { s.Reused++ })
+			return entry, nil
+		}
+	}
+
+	if d.MaxSize > 0 && expectedSize > d.MaxSize {
+		d.count(func(s *Stats) { s.Skipped++ })
+		return Entry{}, ErrTooLarge
+	}
+
+	entry, err := d.download(ctx, key, rawURL, expectedSize)
+	if err != nil {
+		d.count(func(s *Stats) {
+			if errors.Is(err, ErrTooLarge) {
+				s.Skipped++
+			} else {
+				s.Failed++
+			}
+		})
+		return Entry{}, err
+	}
+
+	d.mu.Lock()
+	d.index[key] = entry
+	d.stats.Downloaded++
+	d.stats.Bytes += entry.Size
+	d.mu.Unlock()
+	return entry, nil
+}
+
+// lockKey locks a key for fetching and returns the function that unlocks it
+func (d *Downloader) lockKey(key string) func() {
+	d.mu.Lock()
+	if d.fetching == nil {
+		d.fetching = make(map[string]*keyLock)
+	}
+	l, ok := d.fetching[key]
+	if !ok {
+		l = &keyLock{}
+		d.fetching[key] = l
+	}
+	l.refs++
+	d.mu.Unlock()
+
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+		d.mu.Lock()
+		if l.refs--; l.refs == 0 {
+			delete(d.fetching, key)
+		}
+		d.mu.Unlock()
+	}
+}
+
+func (d *Downloader) lookup(key string) (Entry, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	entry, ok := d.index[key]
+	return entry, ok
+}
+
+func (d *Downloader) count(fn func(*Stats)) {
+	d.mu.Lock()
+	fn(&d.stats)
+	d.mu.Unlock()
+}
+
+// verify checks that a completed download is still on disk with the right checksum
+func (d *Downloader) verify(entry Entry) error {
+	f, err := os.Open(filepath.Join(d.Dir, filepath.FromSlash(entry.Path)))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != entry.SHA256 {
+		return fmt.Errorf("checksum mismatch for %s: got %s", entry.Path, sum)
+	}
+	return nil
+}
+
+// download fetches a file into the partial directory, resuming a previous attempt if there is one,
+// then moves it to its content address
+func (d *Downloader) download(ctx context.Context, key, rawURL string, expectedSize int64) (Entry, error) {
+	keySum := sha256.Sum256([]byte(key))
+	partial := filepath.Join(d.Dir, partialDir, hex.EncodeToString(keySum[:]))
+
+	f, err := os.OpenFile(partial, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return Entry{}, err
+	}
+	defer f.Close()
+
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return Entry{}, err
+	}
+	if expectedSize > 0 && offset > expectedSize {
+		// Left over from a different file, start over
+		if err := f.Truncate(0); err != nil {
+			return Entry{}, err
+		}
+		offset, _ = f.Seek(0, io.SeekStart)
+	}
+
+	if expectedSize == 0 || offset < expectedSize {
+		if err := d.get(ctx, rawURL, f, offset); err != nil {
+			if errors.Is(err, ErrTooLarge) {
+				f.Close()
+				os.Remove(partial)
+			}
+			return Entry{}, err
+		}
+	}
+
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return Entry{}, err
+	}
+	if expectedSize > 0 && size != expectedSize {
+		f.Close()
+		os.Remove(partial)
+		return Entry{}, fmt.Errorf("downloaded %d bytes of %s, expected %d", size, rawURL, expectedSize)
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return Entry{}, err
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return Entry{}, err
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+
+	rel := path.Join(sum[:2], sum+extension(rawURL))
+	dest := filepath.Join(d.Dir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return Entry{}, err
+	}
+	f.Close()
+	if err := os.Rename(partial, dest); err != nil {
+		return Entry{}, err
+	}
+
+	return Entry{Path: rel, SHA256: sum, Size: size, URL: rawURL}, nil
+}
+
+// get appends the body of rawURL from offset onwards to f
+func (d *Downloader) get(ctx context.Context, rawURL string, f *os.File, offset int64) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error downloading %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// The server ignored the range, start over
+		if err := f.Truncate(0); err != nil {
+			return err
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		offset = 0
+	case http.StatusPartialContent:
+	case http.StatusRequestedRangeNotSatisfiable:
+		// Already complete
+		return nil
+	default:
+		return fmt.Errorf("error downloading %s: %s", rawURL, resp.Status)
+	}
+
+	if d.MaxSize > 0 && resp.ContentLength > 0 && offset+resp.ContentLength > d.MaxSize {
+		return ErrTooLarge
+	}
+
+	body := io.Reader(resp.Body)
+	if d.MaxSize > 0 {
+		// Read one byte past the cap to detect oversized bodies without a Content-Length
+		body = io.LimitReader(resp.Body, d.MaxSize-offset+1)
+	}
+
+	n, err := io.Copy(f, body)
+	if err != nil {
+		return fmt.Errorf("error downloading %s: %w", rawURL, err)
+	}
+	if d.MaxSize > 0 && offset+n > d.MaxSize {
+		return ErrTooLarge
+	}
+	return nil
+}
+
+// extension returns the file extension of a URL's path, if it looks like one
+func extension(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	ext := strings.ToLower(path.Ext(u.Path))
+	if len(ext) > 10 || strings.ContainsAny(ext, " %") {
+		return ""
+	}
+	if ext == "" {
+		if exts, _ := mime.ExtensionsByType(u.Query().Get("format")); len(exts) > 0 {
+			return exts[0]
+		}
+	}
+	return ext
+}
@@ -0,0 +1,157 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fileServer serves content, counting requests and honouring range requests
+func fileServer(t *testing.T, content []byte, delay time.Duration) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		body := content
+		status := http.StatusOK
+		if rng, ok := strings.CutPrefix(r.Header.Get("Range"), "bytes="); ok {
+			if offset, err := strconv.Atoi(strings.TrimSuffix(rng, "-")); err == nil && offset < len(content) {
+				body = content[offset:]
+				status = http.StatusPartialContent
+			}
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(status)
+		// Written in two halves, so concurrent downloads would interleave
+		half := len(body) / 2
+		w.Write(body[:half])
+		w.(http.Flusher).Flush()
+		time.Sleep(delay)
+		w.Write(body[half:])
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func TestFetchSameKeyConcurrently(t *testing.T) {
+	content := bytes.Repeat([]byte("sticker "), 4096)
+	srv, requests := fileServer(t, content, 20*time.Millisecond)
+
+	d, err := NewDownloader(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	entries := make([]Entry, 8)
+	errs := make([]error, 8)
+	for i := range entries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			entries[i], errs[i] = d.Fetch(context.Background(), "sticker:1", srv.URL+"/1.png", 0)
+		}()
+	}
+	wg.Wait()
+
+	sum := sha256.Sum256(content)
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("fetch %d: %v", i, err)
+		}
+		if entries[i].SHA256 != hex.EncodeToString(sum[:]) {
+			t.Fatalf("fetch %d stored %s, want the checksum of the content", i, entries[i].SHA256)
+		}
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("made %d requests, want 1", n)
+	}
+	stored, err := os.ReadFile(filepath.Join(d.Dir, filepath.FromSlash(entries[0].Path)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(stored, content) {
+		t.Errorf("stored %d bytes that differ from the %d served", len(stored), len(content))
+	}
+	if s := d.Stats(); s.Downloaded != 1 || s.Reused != 7 {
+		t.Errorf("stats = %+v, want 1 downloaded and 7 reused", s)
+	}
+}
+
+func TestFetchResumesAndReuses(t *testing.T) {
+	content := []byte("0123456789abcdefghij")
+	srv, requests := fileServer(t, content, 0)
+
+	d, err := NewDownloader(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An interrupted earlier run left the first half behind
+	keySum := sha256.Sum256([]byte("attachment:9"))
+	partial := filepath.Join(d.Dir, partialDir, hex.EncodeToString(keySum[:]))
+	if err := os.WriteFile(partial, content[:10], 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := d.Fetch(context.Background(), "attachment:9", srv.URL+"/file.txt", int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := os.ReadFile(filepath.Join(d.Dir, filepath.FromSlash(entry.Path)))
+	if !bytes.Equal(stored, content) {
+		t.Errorf("resumed file = %q, want %q", stored, content)
+	}
+	if filepath.Ext(entry.Path) != ".txt" {
+		t.Errorf("path %s lost the extension", entry.Path)
+	}
+
+	if err := d.SaveIndex(); err != nil {
+		t.Fatal(err)
+	}
+	again, err := NewDownloader(d.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := again.Fetch(context.Background(), "attachment:9", srv.URL+"/file.txt", int64(len(content))); err != nil {
+		t.Fatal(err)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("made %d requests, want the second run to reuse the file", n)
+	}
+}
+
+func TestFetchTooLarge(t *testing.T) {
+	srv, requests := fileServer(t, make([]byte, 100), 0)
+
+	d, err := NewDownloader(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.MaxSize = 50
+
+	if _, err := d.Fetch(context.Background(), "attachment:1", srv.URL+"/big.bin", 100); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("known size: err = %v, want ErrTooLarge", err)
+	}
+	if n := requests.Load(); n != 0 {
+		t.Errorf("made %d requests for a file known to be too large", n)
+	}
+	if _, err := d.Fetch(context.Background(), "url:big", srv.URL+"/big.bin", 0); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("unknown size: err = %v, want ErrTooLarge", err)
+	}
+	if s := d.Stats(); s.Skipped != 2 {
+		t.Errorf("stats = %+v, want 2 skipped", s)
+	}
+}
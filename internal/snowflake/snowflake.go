@@ -0,0 +1,118 @@
+// Package snowflake handles Discord IDs, which are snowflakes: 64-bit numbers
+// encoding when and where they were generated.
+//
+//	 63                         22 21     17 16     12 11          0
+//	| ms since Epoch              | worker  | process | increment    |
+package snowflake
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Epoch is the first second of 2015 in Unix milliseconds, the epoch of Discord snowflakes
+const Epoch = 1420070400000
+
+const (
+	timestampShift = 22
+	workerShift    = 17
+	processShift   = 12
+	fieldMask      = 0x1f
+	incrementMask  = 0xfff
+)
+
+// Snowflake is a Discord ID. It marshals to JSON as a string, like the API sends IDs,
+// so it can replace string ID fields. The zero Snowflake is an unset ID.
+type Snowflake uint64
+
+// Parse parses a snowflake from its decimal string form.
+func Parse(s string) (Snowflake, error) {
+	if s == "" {
+		return 0, fmt.Errorf("invalid snowflake: empty")
+	}
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid snowflake %q: %w", s, err)
+	}
+	return Snowflake(v), nil
+}
+
+// Valid reports whether s is a snowflake in decimal string form.
+func Valid(s string) bool {
+	_, err := Parse(s)
+	return err == nil
+}
+
+// FromTime returns the smallest snowflake created at t, for use in before/after queries.
+// Times before Epoch give 0.
+func FromTime(t time.Time) Snowflake {
+	ms := max(t.UnixMilli()-Epoch, 0)
+	return Snowflake(uint64(ms) << timestampShift)
+}
+
+// String returns the snowflake in decimal, as the API uses it.
+func (s Snowflake) String() string {
+	return strconv.FormatUint(uint64(s), 10)
+}
+
+// IsZero reports whether the snowflake is unset.
+func (s Snowflake) IsZero() bool {
+	return s == 0
+}
+
+// Time returns when the snowflake was created, in UTC with millisecond precision.
+func (s Snowflake) Time() time.Time {
+	return time.UnixMilli(int64(s>>timestampShift) + Epoch).UTC()
+}
+
+// WorkerID returns the internal ID of the worker that generated the snowflake.
+func (s Snowflake) WorkerID() uint8 {
+	return uint8(s >> workerShift & fieldMask)
+}
+
+// ProcessID returns the internal ID of the process that generated the snowflake.
+func (s Snowflake) ProcessID() uint8 {
+	return uint8(s >> processShift & fieldMask)
+}
+
+// Increment returns the snowflake's sequence number, incremented for every ID its process generates.
+func (s Snowflake) Increment() uint16 {
+	return uint16(s & incrementMask)
+}
+
+// MarshalJSON encodes the snowflake as a JSON string, and an unset one as "",
+// the same as an empty string ID field.
+func (s Snowflake) MarshalJSON() ([]byte, error) {
+	if s.IsZero() {
+		return []byte(`""`), nil
+	}
+	return strconv.AppendQuote(nil, s.String()), nil
+}
+
+// UnmarshalJSON decodes a snowflake from a JSON string or number.
+// null and "" decode as 0, like an absent string ID.
+func (s *Snowflake) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		*s = 0
+		return nil
+	}
+	text := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		var err error
+		if text, err = strconv.Unquote(text); err != nil {
+			return fmt.Errorf("invalid snowflake %s: %w", data, err)
+		}
+		if text == "" {
+			*s = 0
+			return nil
+		}
+	}
+	v, err := Parse(text)
+	if err != nil {
+		return err
+	}
+	*s = v
+	return nil
+}
@@ -0,0 +1,107 @@
+package snowflake
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestTime(t *testing.T) {
+	// The ID of the Discord API docs' example user
+	s, err := Parse("175928847299117063")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2016, 4, 30, 11, 18, 25, 796e6, time.UTC)
+	if got := s.Time(); !got.Equal(want) || got.Location() != time.UTC {
+		t.Errorf("Time() = %v, want %v", got, want)
+	}
+}
+
+func TestFromTime(t *testing.T) {
+	at := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	s := FromTime(at)
+	if !s.Time().Equal(at) {
+		t.Errorf("FromTime(%v).Time() = %v", at, s.Time())
+	}
+	if got, err := Parse(s.String()); err != nil || got != s {
+		t.Errorf("Parse(%q) = %v, %v", s.String(), got, err)
+	}
+	if s := FromTime(time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)); s != 0 {
+		t.Errorf("FromTime before Epoch = %v, want 0", s)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, s := range []string{"", "abc", "-1", "1.5"} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("Parse(%q) succeeded", s)
+		}
+	}
+}
+
+func TestFields(t *testing.T) {
+	s, err := Parse("175928847299117063")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.WorkerID(); got != 1 {
+		t.Errorf("WorkerID() = %d, want 1", got)
+	}
+	if got := s.ProcessID(); got != 0 {
+		t.Errorf("ProcessID() = %d, want 0", got)
+	}
+	if got := s.Increment(); got != 7 {
+		t.Errorf("Increment() = %d, want 7", got)
+	}
+	if s.IsZero() || !Snowflake(0).IsZero() {
+		t.Errorf("IsZero() is wrong for %v or 0", s)
+	}
+}
+
+func TestValid(t *testing.T) {
+	for id, want := range map[string]bool{"175928847299117063": true, "0": true, "": false, "abc": false, "-1": false} {
+		if got := Valid(id); got != want {
+			t.Errorf("Valid(%q) = %v, want %v", id, got, want)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	type object struct {
+		ID     Snowflake `json:"id"`
+		Parent Snowflake `json:"parent_id,omitempty"`
+	}
+
+	for _, tt := range []struct {
+		in   string
+		want Snowflake
+	}{
+		{`{"id":"175928847299117063"}`, 175928847299117063},
+		{`{"id":175928847299117063}`, 175928847299117063},
+		{`{"id":null}`, 0},
+		{`{"id":""}`, 0},
+	} {
+		var o object
+		if err := json.Unmarshal([]byte(tt.in), &o); err != nil || o.ID != tt.want {
+			t.Errorf("Unmarshal(%s) = %v, %v, want %v", tt.in, o.ID, err, tt.want)
+		}
+	}
+	for _, in := range []string{`{"id":"abc"}`, `{"id":true}`, `{"id":-1}`} {
+		var o object
+		if err := json.Unmarshal([]byte(in), &o); err == nil {
+			t.Errorf("Unmarshal(%s) = %v, want an error", in, o.ID)
+		}
+	}
+
+	// IDs are written as strings like Discord's, and a zero ID as an empty one
+	for o, want := range map[object]string{
+		{ID: 175928847299117063}: `{"id":"175928847299117063"}`,
+		{}:                       `{"id":""}`,
+	} {
+		got, err := json.Marshal(o)
+		if err != nil || string(got) != want {
+			t.Errorf("Marshal(%+v) = %s, %v, want %s", o, got, err, want)
+		}
+	}
+}